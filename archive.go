@@ -0,0 +1,323 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// archive.go source file implements the common support for the archive
+// locators, and the Loader and File interface for files in an archive.
+
+package data
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveLocator implements the archive locators, like "zip" and "tar", that
+// locate a module in an archive, using the same layout as the user data
+// directory.
+type archiveLocator struct {
+	name string
+	path string // absolute path to the archive
+	ar   *archive
+}
+
+// newArchiveLocator returns a new archive locator with the specified name, for
+// modules stored in the archive ar read from path.
+//
+// newArchiveLocator returns the "null" locator if the main module is not
+// stored in the archive.
+func newArchiveLocator(name, path string, ar *archive) Locator {
+	info := loadInfo()
+
+	l := &archiveLocator{
+		name: name,
+		path: path,
+		ar:   ar,
+	}
+
+	// Check if the main module is in the archive.
+	if _, err := l.locate(info.Main.Path); err != nil {
+		return &nullLocator{
+			err: fmt.Errorf("main module %s is not in the archive %s",
+				&info.Main, path),
+		}
+	}
+
+	return l
+}
+
+// Locate implements the Locator interface.
+func (l *archiveLocator) Locate(modpath string) (Loader, error) {
+	ld, err := l.locate(modpath)
+	if err != nil {
+		return nil, mkerr(l, err)
+	}
+
+	return ld, nil
+}
+
+func (l *archiveLocator) locate(modpath string) (Loader, error) {
+	info := loadInfo()
+
+	// Find module in build info.
+	mod, err := find(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirpath string
+	if modpath == info.Main.Path {
+		// The main module is special, and the data is stored in $APPNAME.
+		dirpath = AppName()
+	} else {
+		// Active modules are stored in go-data, with the fully versioned path
+		// flattened.
+		dirpath = "go-data/" + mod.FlatPath()
+	}
+
+	if !l.ar.isDir(dirpath) {
+		return nil, fmt.Errorf("module %s is not in the archive", modpath)
+	}
+
+	// It is responsibility of Loader to report an error if the data directory
+	// does not exists.
+	ld := &archiveLoader{
+		lc:   l,
+		mod:  mod,
+		ar:   l.ar,
+		root: dirpath + "/data",
+	}
+
+	return ld, nil
+}
+
+// Name implements the Locator interface.
+func (l archiveLocator) Name() string {
+	return l.name
+}
+
+// archiveEntry represents a regular file or symbolic link in an archive.
+type archiveEntry interface {
+	// FileInfo returns information about the entry.
+	FileInfo() os.FileInfo
+
+	// Open returns a reader for the entry content.
+	Open() (io.ReadCloser, error)
+}
+
+// archive is an index of the files in an archive.
+type archive struct {
+	files map[string]archiveEntry // regular files and symbolic links
+	dirs  map[string]os.FileInfo  // directories, nil if implied
+}
+
+// newArchive returns a new empty archive.
+func newArchive() *archive {
+	ar := &archive{
+		files: make(map[string]archiveEntry),
+		dirs:  make(map[string]os.FileInfo),
+	}
+
+	return ar
+}
+
+// addFile adds the entry named by name to the archive.
+func (ar *archive) addFile(name string, e archiveEntry) {
+	name = path.Clean(name)
+	ar.files[name] = e
+	ar.addParents(name)
+}
+
+// addDir adds the directory named by name, with information fi, to the
+// archive.
+func (ar *archive) addDir(name string, fi os.FileInfo) {
+	name = path.Clean(name)
+	ar.dirs[name] = fi
+	ar.addParents(name)
+}
+
+// addParents adds the parent directories implied by name.
+func (ar *archive) addParents(name string) {
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if _, ok := ar.dirs[dir]; ok {
+			break
+		}
+		ar.dirs[dir] = nil
+	}
+}
+
+// isDir returns true if name is a directory in the archive.
+func (ar *archive) isDir(name string) bool {
+	_, ok := ar.dirs[name]
+
+	return ok
+}
+
+// archiveLoader implements a Loader that loads module data from an archive.
+type archiveLoader struct {
+	lc   Locator
+	mod  *Module
+	ar   *archive
+	root string // path to the module data directory, in the archive
+}
+
+// Load implements the Loader interface.
+func (l *archiveLoader) Load(path string) (File, error) {
+	f, err := l.load(path)
+	if err != nil {
+		return nil, mkerr(l.lc, l, err)
+	}
+
+	return f, nil
+}
+
+func (l *archiveLoader) load(name string) (File, error) {
+	if filepath.IsAbs(name) || path.IsAbs(name) {
+		return nil, fmt.Errorf("path %s is not a relative path", name)
+	}
+	if !l.ar.isDir(l.root) {
+		return nil, fmt.Errorf("module %v does not have data", l.mod)
+	}
+
+	// It is responsibility of File to report an error if path does not exists.
+	file := &archiveFile{
+		lc:   l.lc,
+		ld:   l,
+		ar:   l.ar,
+		root: l.root,
+		path: name,
+	}
+
+	return file, nil
+}
+
+// Module implements the Loader interface.
+func (l *archiveLoader) Module() *Module {
+	return l.mod
+}
+
+// glob implements the globber interface.
+func (l *archiveLoader) glob(pattern string) ([]string, error) {
+	if !l.ar.isDir(l.root) {
+		err := fmt.Errorf("module %v does not have data", l.mod)
+
+		return nil, mkerr(l.lc, l, err)
+	}
+
+	m := newMatcher(pattern)
+	prefix := l.root + "/"
+	for name, e := range l.ar.files {
+		if !strings.HasPrefix(name, prefix) || !e.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		m.add(name[len(prefix):])
+	}
+
+	return m.result(), nil
+}
+
+// archiveFile represents a file in an archive.
+type archiveFile struct {
+	lc   Locator
+	ld   Loader
+	ar   *archive
+	root string // path to the module data directory, in the archive
+	path string // path to the data file, relative to the module data directory
+}
+
+// Name implements the File interface.
+func (f *archiveFile) Name() string {
+	return f.path
+}
+
+// Path implements the File interface.  The file is stored in an archive, so
+// Path always returns an empty string.
+func (f *archiveFile) Path() string {
+	return ""
+}
+
+// Lstat implements the File interface.
+func (f *archiveFile) Lstat() (os.FileInfo, error) {
+	name := f.name()
+	if e, ok := f.ar.files[name]; ok {
+		return e.FileInfo(), nil
+	}
+	if fi, ok := f.ar.dirs[name]; ok {
+		if fi == nil {
+			fi = &dirInfo{name: path.Base(name)}
+		}
+
+		return fi, nil
+	}
+
+	return nil, mkerr(f.lc, f.ld, f, "lstat", os.ErrNotExist)
+}
+
+// Open implements the File interface.
+func (f *archiveFile) Open() (io.ReadCloser, error) {
+	name := f.name()
+	e, ok := f.ar.files[name]
+	if !ok {
+		if f.ar.isDir(name) {
+			return nil, mkerr(f.lc, f.ld, f, "open", errors.New("is a directory"))
+		}
+
+		return nil, mkerr(f.lc, f.ld, f, "open", os.ErrNotExist)
+	}
+	if !e.FileInfo().Mode().IsRegular() {
+		return nil, mkerr(f.lc, f.ld, f, "open", errors.New("not a regular file"))
+	}
+
+	rc, err := e.Open()
+	if err != nil {
+		return nil, mkerr(f.lc, f.ld, f, "open", err)
+	}
+
+	return rc, nil
+}
+
+// OpenRandom implements the RandomFile interface.  The content of the files
+// stored compressed in a zip archive is read in memory.
+func (f *archiveFile) OpenRandom() (RandomReader, error) {
+	zf, ok := f.ar.files[f.name()].(*zip.File)
+	if !ok || zf.Method != zip.Store || !zf.Mode().IsRegular() {
+		return readRandom(f)
+	}
+
+	// The raw content of a stored file is the file content.
+	raw, err := zf.OpenRaw()
+	if err != nil {
+		return nil, mkerr(f.lc, f.ld, f, "open", err)
+	}
+	sr, ok := raw.(*io.SectionReader)
+	if !ok {
+		return readRandom(f)
+	}
+
+	return nopCloser{sr}, nil
+}
+
+// name returns the name of the file in the archive.
+func (f *archiveFile) name() string {
+	return path.Join(f.root, filepath.ToSlash(f.path))
+}
+
+// dirInfo implements os.FileInfo for a directory implied by the files in an
+// archive.
+type dirInfo struct {
+	name string
+}
+
+func (fi *dirInfo) Name() string       { return fi.name }
+func (fi *dirInfo) Size() int64        { return 0 }
+func (fi *dirInfo) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (fi *dirInfo) ModTime() time.Time { return time.Time{} }
+func (fi *dirInfo) IsDir() bool        { return true }
+func (fi *dirInfo) Sys() interface{}   { return nil }
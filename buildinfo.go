@@ -7,10 +7,25 @@ package data
 import (
 	"runtime/debug"
 	"strings"
+	"sync/atomic"
 )
 
-// info stores the value returned by readBuildInfo.  It can be nil.
-var info *buildInfo
+// info stores the *buildInfo value returned by readBuildInfo, or replaced by
+// the datatest package.  It is an atomic.Value, so that tests can replace it
+// while other goroutines read it.
+var info atomic.Value
+
+// loadInfo returns the current build info.  It can be nil.
+func loadInfo() *buildInfo {
+	bi, _ := info.Load().(*buildInfo)
+
+	return bi
+}
+
+// storeInfo sets the current build info to bi.
+func storeInfo(bi *buildInfo) {
+	info.Store(bi)
+}
 
 // buildInfo represents the build information read from the running binary.
 type buildInfo struct {
@@ -54,7 +69,7 @@ func fromDebug(m *debug.Module) Module {
 		Version: m.Version,
 		Sum:     m.Sum,
 	}
-	if mod.Replace != nil {
+	if m.Replace != nil {
 		// Replace is not recursive.
 		mod.Replace = &Module{
 			Path:    m.Replace.Path,
@@ -0,0 +1,382 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// cache.go source file implements a Locator and Loader that cache the file
+// contents in memory.
+
+package data
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// entryOverhead is the approximate memory used by a cache entry, excluding
+// the key and the file content.
+const entryOverhead = 128
+
+// cacheLocator implements a Locator that returns caching loaders.
+type cacheLocator struct {
+	lc    Locator
+	cache *lru
+
+	mu      sync.Mutex
+	loaders map[string]*cacheLoader // by module path
+	calls   map[string]*locateCall  // in flight lookups, by module path
+}
+
+// locateCall is an in flight module lookup.
+type locateCall struct {
+	wg   sync.WaitGroup
+	ld   *cacheLoader
+	err  error
+	dups int // number of callers waiting for the lookup
+}
+
+// NewCachingLocator returns a Locator that wraps l, returning loaders that
+// cache the file contents and the failed lookups in memory, as done by
+// NewCachingLoader.  All the loaders share the same cache, with a maximum
+// size of max bytes.
+//
+// The returned locator returns the same loader for the same module, so that
+// the cache is used by the Load function, when the default locator is set to
+// a caching locator.  Concurrent lookups of the same module are coalesced,
+// and the lookups of different modules do not block each other.  Only the
+// successful lookups are remembered, so there is at most one loader for each
+// active module:
+//
+//	data.SetDefaultLocator(data.NewCachingLocator(data.DefaultLocator(), 64<<20))
+func NewCachingLocator(l Locator, max int64) Locator {
+	c := &cacheLocator{
+		lc:      l,
+		cache:   newLRU(max),
+		loaders: make(map[string]*cacheLoader),
+		calls:   make(map[string]*locateCall),
+	}
+
+	return c
+}
+
+// Locate implements the Locator interface.
+func (l *cacheLocator) Locate(modpath string) (Loader, error) {
+	l.mu.Lock()
+	if ld, ok := l.loaders[modpath]; ok {
+		l.mu.Unlock()
+
+		return ld, nil
+	}
+	if call, ok := l.calls[modpath]; ok {
+		call.dups++
+		l.mu.Unlock()
+		call.wg.Wait()
+
+		return call.loader()
+	}
+	call := new(locateCall)
+	call.wg.Add(1)
+	l.calls[modpath] = call
+	l.mu.Unlock()
+
+	// The wrapped locator may access the network, as an example to download
+	// the module, so it is called without holding the lock.  The error is
+	// already reported by the wrapped locator.
+	ld, err := l.lc.Locate(modpath)
+	if err == nil {
+		call.ld = &cacheLoader{
+			ld:    ld,
+			cache: l.cache,
+		}
+	}
+	call.err = err
+	call.wg.Done()
+
+	l.mu.Lock()
+	delete(l.calls, modpath)
+	if err == nil {
+		l.loaders[modpath] = call.ld
+	}
+	l.mu.Unlock()
+
+	return call.loader()
+}
+
+// loader returns the result of the lookup.
+func (c *locateCall) loader() (Loader, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return c.ld, nil
+}
+
+// Name implements the Locator interface.  It returns the name of the wrapped
+// locator.
+func (l *cacheLocator) Name() string {
+	return l.lc.Name()
+}
+
+// cacheLoader implements a Loader that caches the file contents in memory.
+type cacheLoader struct {
+	ld    Loader
+	cache *lru
+}
+
+// NewCachingLoader returns a Loader that wraps ld, caching the file contents
+// and the failed lookups in memory, using a least recently used cache with a
+// maximum size of max bytes.  Files larger than max are never cached.
+//
+// Concurrent calls to File.Lstat or File.Open for the same file are
+// coalesced, so that the file is read only once.
+func NewCachingLoader(ld Loader, max int64) Loader {
+	l := &cacheLoader{
+		ld:    ld,
+		cache: newLRU(max),
+	}
+
+	return l
+}
+
+// Load implements the Loader interface.
+func (l *cacheLoader) Load(name string) (File, error) {
+	f, err := l.ld.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key uses the cleaned path, so that the same file is cached only
+	// once.
+	file := &cacheFile{
+		f:     f,
+		key:   l.ld.Module().String() + "\x00" + path.Clean(name),
+		cache: l.cache,
+	}
+
+	return file, nil
+}
+
+// Module implements the Loader interface.
+func (l *cacheLoader) Module() *Module {
+	return l.ld.Module()
+}
+
+// glob implements the globber interface.  The names are not cached.
+func (l *cacheLoader) glob(pattern string) ([]string, error) {
+	return Glob(l.ld, pattern)
+}
+
+// cacheFile represents a file whose content is cached in memory.
+type cacheFile struct {
+	f     File
+	key   string // cache key, without the operation suffix
+	cache *lru
+}
+
+// Name implements the File interface.
+func (f *cacheFile) Name() string {
+	return f.f.Name()
+}
+
+// Path implements the File interface.
+func (f *cacheFile) Path() string {
+	return f.f.Path()
+}
+
+// Lstat implements the File interface.
+func (f *cacheFile) Lstat() (os.FileInfo, error) {
+	e, _ := f.cache.do(f.key+"\x00lstat", func() (*cacheEntry, io.ReadCloser) {
+		fi, err := f.f.Lstat()
+
+		return &cacheEntry{fi: fi, err: err}, nil
+	})
+
+	return e.fi, e.err
+}
+
+// Open implements the File interface.
+func (f *cacheFile) Open() (io.ReadCloser, error) {
+	e, rc := f.cache.do(f.key+"\x00open", func() (*cacheEntry, io.ReadCloser) {
+		rc, err := f.f.Open()
+		if err != nil {
+			return &cacheEntry{err: err}, nil
+		}
+
+		// Read at most max bytes, so that large files are not read in memory.
+		buf, err := io.ReadAll(io.LimitReader(rc, f.cache.max+1))
+		if err != nil {
+			rc.Close()
+
+			return &cacheEntry{err: err, nocache: true}, nil
+		}
+		if int64(len(buf)) > f.cache.max {
+			r := io.MultiReader(bytes.NewReader(buf), rc)
+
+			return &cacheEntry{nocache: true}, &readCloser{r, rc}
+		}
+		rc.Close()
+
+		return &cacheEntry{data: buf}, nil
+	})
+	switch {
+	case rc != nil:
+		return rc, nil
+	case e == nil:
+		// The file is not cacheable, and it has been read by another
+		// goroutine.
+		return f.f.Open()
+	case e.err != nil:
+		return nil, e.err
+	}
+
+	// The returned reader is a RandomReader.
+	return nopCloser{bytes.NewReader(e.data)}, nil
+}
+
+// readCloser combines an io.Reader and an io.Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// cacheEntry is an entry in the cache.
+type cacheEntry struct {
+	fi      os.FileInfo
+	data    []byte
+	err     error
+	nocache bool // do not store the entry in the cache
+}
+
+// size returns the approximate memory used by the entry.
+func (e *cacheEntry) size() int64 {
+	return int64(len(e.data)) + entryOverhead
+}
+
+// lru is a least recently used cache with coalescing of concurrent loads.
+type lru struct {
+	max int64 // maximum size, in bytes
+
+	mu    sync.Mutex
+	size  int64
+	gen   int64                    // incremented by remove
+	ll    *list.List               // of *lruItem, most recently used first
+	items map[string]*list.Element // by key
+	calls map[string]*lruCall      // in flight loads, by key
+}
+
+// lruItem is an item in the lru list.
+type lruItem struct {
+	key   string
+	entry *cacheEntry
+}
+
+// lruCall is an in flight load.
+type lruCall struct {
+	wg    sync.WaitGroup
+	entry *cacheEntry
+	dups  int // number of callers waiting for the load
+}
+
+// newLRU returns a new lru cache with a maximum size of max bytes.
+func newLRU(max int64) *lru {
+	c := &lru{
+		max:   max,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		calls: make(map[string]*lruCall),
+	}
+
+	return c
+}
+
+// do returns the entry for key, calling load if it is not in the cache.  Only
+// one load for the same key is in flight at a given time; concurrent callers
+// wait for the load to complete and get the same entry.
+//
+// load may return a non nil io.ReadCloser for a non cacheable entry, that is
+// returned only to the caller that called load.  In this case, the other
+// callers get a nil entry.
+//
+// Errors are cached only if they report that the file does not exist.
+func (c *lru) do(key string, load func() (*cacheEntry, io.ReadCloser)) (*cacheEntry, io.ReadCloser) {
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		c.ll.MoveToFront(elem)
+		c.mu.Unlock()
+
+		return elem.Value.(*lruItem).entry, nil
+	}
+	if call, ok := c.calls[key]; ok {
+		call.dups++
+		c.mu.Unlock()
+		call.wg.Wait()
+
+		return call.entry, nil
+	}
+	call := new(lruCall)
+	call.wg.Add(1)
+	c.calls[key] = call
+	gen := c.gen
+	c.mu.Unlock()
+
+	entry, rc := load()
+	if rc == nil {
+		call.entry = entry
+	}
+	call.wg.Done()
+
+	c.mu.Lock()
+	delete(c.calls, key)
+	// The entry may be stale, if the cache has been invalidated during the
+	// load.
+	cacheable := !entry.nocache && gen == c.gen &&
+		(entry.err == nil || errors.Is(entry.err, os.ErrNotExist))
+	if cacheable {
+		c.add(key, entry)
+	}
+	c.mu.Unlock()
+
+	return entry, rc
+}
+
+// add adds the entry to the cache, evicting the least recently used entries
+// if necessary.  add must be called with c.mu held.
+func (c *lru) add(key string, entry *cacheEntry) {
+	size := entry.size() + int64(len(key))
+	if size > c.max {
+		return
+	}
+
+	elem := c.ll.PushFront(&lruItem{key, entry})
+	c.items[key] = elem
+	c.size += size
+	for c.size > c.max {
+		elem := c.ll.Back()
+		item := elem.Value.(*lruItem)
+		c.ll.Remove(elem)
+		delete(c.items, item.key)
+		c.size -= item.entry.size() + int64(len(item.key))
+	}
+}
+
+// remove removes the entries whose key has the specified prefix.
+func (c *lru) remove(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for key, elem := range c.items {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		item := elem.Value.(*lruItem)
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		c.size -= item.entry.size() + int64(len(key))
+	}
+}
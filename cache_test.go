@@ -0,0 +1,311 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingLoader is a Loader that counts the calls to File.Open.
+type countingLoader struct {
+	Loader
+	gate chan struct{} // if not nil, Open waits until it is closed
+	err  error         // if not nil, returned by Open
+
+	mu    sync.Mutex
+	opens map[string]int // by file name
+}
+
+// newCountingLoader returns a countingLoader for the files in dir, with the
+// specified content.
+func newCountingLoader(t *testing.T, files map[string]string) *countingLoader {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := &countingLoader{
+		Loader: &fsLoader{
+			lc:   &nullLocator{},
+			mod:  &Module{Path: "example.com/m", Version: "v1.0.0"},
+			root: dir,
+		},
+		opens: make(map[string]int),
+	}
+
+	return l
+}
+
+// Load implements the Loader interface.
+func (l *countingLoader) Load(name string) (File, error) {
+	f, err := l.Loader.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &countingFile{f, l}, nil
+}
+
+// count returns the number of calls to Open for the file named by name.
+func (l *countingLoader) count(name string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.opens[name]
+}
+
+// countingFile is a File that counts the calls to Open.
+type countingFile struct {
+	File
+	l *countingLoader
+}
+
+// Open implements the File interface.
+func (f *countingFile) Open() (io.ReadCloser, error) {
+	f.l.mu.Lock()
+	f.l.opens[filepath.ToSlash(filepath.Clean(f.Name()))]++
+	f.l.mu.Unlock()
+
+	if f.l.gate != nil {
+		<-f.l.gate
+	}
+	if f.l.err != nil {
+		return nil, f.l.err
+	}
+
+	return f.File.Open()
+}
+
+func TestCacheEviction(t *testing.T) {
+	content := strings.Repeat("x", 100)
+	cl := newCountingLoader(t, map[string]string{
+		"a":     content,
+		"b":     content,
+		"c":     content,
+		"large": strings.Repeat("x", 1000),
+	})
+
+	// The size of each entry is the content size, the entry overhead and the
+	// key size.  The cache can store two entries.
+	key := cl.Module().String() + "\x00a\x00open"
+	max := int64(2 * (len(content) + entryOverhead + len(key)))
+	ld := NewCachingLoader(cl, max)
+	cache := ld.(*cacheLoader).cache
+
+	tests := []struct {
+		name  string
+		count int // number of calls to Open after the load
+	}{
+		{"a", 1},
+		{"b", 1},
+		{"a", 1}, // a is the most recently used
+		{"c", 1}, // b is evicted
+		{"a", 1},
+		{"b", 2}, // c is evicted
+		{"c", 2},
+		{"large", 1},
+		{"large", 2}, // files larger than the cache are never cached
+	}
+	for i, test := range tests {
+		got, err := readAll(ld, test.name)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if want := content; test.name != "large" && got != want {
+			t.Errorf("#%d: %s: got %q, want %q", i, test.name, got, want)
+		}
+		if count := cl.count(test.name); count != test.count {
+			t.Errorf("#%d: %s: got %d calls to Open, want %d", i, test.name,
+				count, test.count)
+		}
+
+		cache.mu.Lock()
+		size := cache.size
+		cache.mu.Unlock()
+		if size > max {
+			t.Errorf("#%d: cache size %d is larger than %d", i, size, max)
+		}
+	}
+}
+
+func TestCacheKeys(t *testing.T) {
+	cl := newCountingLoader(t, map[string]string{
+		"dir/a": "a",
+	})
+	ld := NewCachingLoader(cl, 1<<20)
+
+	// The same file is cached only once.
+	for _, name := range []string{"dir/a", "dir//a", "dir/./a"} {
+		if _, err := readAll(ld, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := cl.count("dir/a"); count != 1 {
+		t.Errorf("got %d calls to Open, want 1", count)
+	}
+
+	// The failed lookups are cached.
+	for i := 0; i < 2; i++ {
+		if _, err := readAll(ld, "missing"); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("missing: got error %v, want %v", err, os.ErrNotExist)
+		}
+	}
+	if count := cl.count("missing"); count != 1 {
+		t.Errorf("missing: got %d calls to Open, want 1", count)
+	}
+}
+
+func TestCacheCoalesce(t *testing.T) {
+	// The error is not cached, so that only coalesced loads share the call to
+	// Open.
+	cl := newCountingLoader(t, map[string]string{
+		"a": "a",
+	})
+	cl.gate = make(chan struct{})
+	cl.err = errors.New("unavailable")
+	ld := NewCachingLoader(cl, 1<<20)
+
+	const n = 8
+	var done sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+
+			_, errs[i] = readAll(ld, "a")
+		}(i)
+	}
+
+	// Open is called only after all the loads wait for the in flight call.
+	c := ld.(*cacheLoader).cache
+	key := cl.Module().String() + "\x00a\x00open"
+	waitFor(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		call, ok := c.calls[key]
+
+		return ok && call.dups == n-1
+	})
+	close(cl.gate)
+	done.Wait()
+
+	if count := cl.count("a"); count != 1 {
+		t.Errorf("got %d calls to Open, want 1", count)
+	}
+	for i, err := range errs {
+		if err != cl.err {
+			t.Errorf("#%d: got error %v, want %v", i, err, cl.err)
+		}
+	}
+}
+
+// gatedLocator is a Locator that counts the calls to Locate, waiting until
+// gate is closed.
+type gatedLocator struct {
+	ld   Loader
+	gate chan struct{}
+
+	mu    sync.Mutex
+	calls int
+}
+
+// Locate implements the Locator interface.
+func (l *gatedLocator) Locate(modpath string) (Loader, error) {
+	l.mu.Lock()
+	l.calls++
+	l.mu.Unlock()
+	<-l.gate
+
+	return l.ld, nil
+}
+
+// Name implements the Locator interface.
+func (l *gatedLocator) Name() string {
+	return "gated"
+}
+
+// count returns the number of calls to Locate.
+func (l *gatedLocator) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.calls
+}
+
+// waitFor waits until cond returns true, failing the test t after a timeout.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for the condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCachingLocatorCoalesce(t *testing.T) {
+	gl := &gatedLocator{
+		ld:   newCountingLoader(t, nil),
+		gate: make(chan struct{}),
+	}
+	lc := NewCachingLocator(gl, 1<<20)
+
+	const n = 8
+	var done sync.WaitGroup
+	loaders := make([]Loader, n)
+	for i := 0; i < n; i++ {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+
+			ld, err := lc.Locate("example.com/m")
+			if err != nil {
+				t.Error(err)
+			}
+			loaders[i] = ld
+		}(i)
+	}
+
+	// Locate returns only after all the lookups wait for the in flight call.
+	cl := lc.(*cacheLocator)
+	waitFor(t, func() bool {
+		cl.mu.Lock()
+		defer cl.mu.Unlock()
+
+		call, ok := cl.calls["example.com/m"]
+
+		return ok && call.dups == n-1
+	})
+	close(gl.gate)
+	done.Wait()
+
+	if calls := gl.count(); calls != 1 {
+		t.Errorf("got %d calls to Locate, want 1", calls)
+	}
+	for i, ld := range loaders {
+		if ld != loaders[0] {
+			t.Errorf("#%d: got a different loader", i)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// caller.go source file implements the loading of module data for the module
+// of the calling package.
+
+package data
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// LocateCaller returns the loader for the module containing the calling
+// package, using the default locator.
+//
+// LocateCaller allows a library to load its own data, regardless of the
+// executable that imports it.
+func LocateCaller() (Loader, error) {
+	modpath, err := callerModule(1)
+	if err != nil {
+		return nil, mkerr(DefaultLocator(), err)
+	}
+
+	return LocateModule(modpath)
+}
+
+// LoadCaller returns the file associated at path for the module containing the
+// calling package, using the default locator.
+//
+// path must be a relative path, without the "data/" prefix.
+func LoadCaller(path string) (File, error) {
+	modpath, err := callerModule(1)
+	if err != nil {
+		return nil, mkerr(DefaultLocator(), err)
+	}
+
+	return LoadModule(modpath, path)
+}
+
+// callerModule returns the path of the module containing the package of the
+// caller.  The argument skip is the number of stack frames to ascend, with 0
+// identifying the caller of callerModule.
+func callerModule(skip int) (string, error) {
+	pc, _, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "", errors.New("caller is not available")
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "", errors.New("caller is not available")
+	}
+
+	return packageModule(funcPackage(fn.Name()))
+}
+
+// funcPackage returns the import path of the package of the function with the
+// specified fully qualified name, as reported by runtime.Func.Name.
+func funcPackage(name string) string {
+	// Remove the type arguments of a generic function, since they may
+	// contain import paths.
+	if idx := strings.IndexByte(name, '['); idx >= 0 {
+		name = name[:idx]
+	}
+
+	// The package name ends at the first dot after the last slash.
+	start := strings.LastIndexByte(name, '/') + 1
+	if idx := strings.IndexByte(name[start:], '.'); idx >= 0 {
+		name = name[:start+idx]
+	}
+
+	// The dots in the last element of the import path are escaped.
+	return strings.Replace(name, "%2e", ".", -1)
+}
+
+// packageModule returns the path of the module containing the package named
+// by pkgpath, using the longest prefix match on the modules reported by build
+// info.
+func packageModule(pkgpath string) (string, error) {
+	info := loadInfo()
+	if info == nil {
+		return "", errors.New("build info is not available")
+	}
+	if pkgpath == "main" {
+		// The main package is always in the main module.
+		return info.Main.Path, nil
+	}
+
+	modpath := ""
+	if hasPathPrefix(pkgpath, info.Main.Path) {
+		modpath = info.Main.Path
+	}
+	for _, mod := range info.Deps {
+		if len(mod.Path) > len(modpath) && hasPathPrefix(pkgpath, mod.Path) {
+			modpath = mod.Path
+		}
+	}
+	if modpath == "" {
+		return "", fmt.Errorf("package %s is not in an active module", pkgpath)
+	}
+
+	return modpath, nil
+}
+
+// hasPathPrefix returns true if the import path s is equal to prefix or it
+// is in the directory tree rooted at prefix.
+func hasPathPrefix(s, prefix string) bool {
+	if !strings.HasPrefix(s, prefix) {
+		return false
+	}
+
+	return len(s) == len(prefix) || s[len(prefix)] == '/'
+}
@@ -0,0 +1,177 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// mapLocator is a Locator for the modules stored in the directories of a
+// map, by module path.
+type mapLocator map[string]string
+
+// Locate implements the Locator interface.
+func (l mapLocator) Locate(modpath string) (Loader, error) {
+	root, ok := l[modpath]
+	if !ok {
+		return nil, mkerr(l, fmt.Errorf("module %s not found", modpath))
+	}
+	mod, err := find(modpath)
+	if err != nil {
+		return nil, mkerr(l, err)
+	}
+	ld := &fsLoader{
+		lc:   l,
+		mod:  mod,
+		root: root,
+	}
+
+	return ld, nil
+}
+
+// Name implements the Locator interface.
+func (l mapLocator) Name() string {
+	return "map"
+}
+
+// setDefaultLocator sets the default locator to l, for the duration of the
+// test t.
+func setDefaultLocator(t *testing.T, l Locator) {
+	old := DefaultLocator()
+	SetDefaultLocator(l)
+	t.Cleanup(func() {
+		SetDefaultLocator(old)
+	})
+}
+
+// callerInfo is the build info used by the caller tests, where this package
+// is a dependency.
+var callerInfo = buildInfo{
+	Path: "example.com/app/cmd/app",
+	Main: Module{Path: "example.com/app", Version: "(devel)"},
+	Deps: []Module{
+		{Path: "example.com/lib", Version: "v1.0.0"},
+		{Path: "example.com/lib/v2", Version: "v2.0.0"},
+		{Path: "example.com/libx", Version: "v1.0.0"},
+		{Path: "github.com/perillo", Version: "v1.0.0"},
+		{Path: "github.com/perillo/data", Version: "v1.0.0"},
+	},
+}
+
+func TestFuncPackage(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"main.main", "main"},
+		{"main.main.func1", "main"},
+		{"github.com/perillo/data.Load", "github.com/perillo/data"},
+		{"github.com/perillo/data.(*fsLoader).Load", "github.com/perillo/data"},
+		{"github.com/perillo/data.Self.func1", "github.com/perillo/data"},
+		{"example.com/a%2eb.F", "example.com/a.b"},
+		{"example.com/a.b/c.F", "example.com/a.b/c"},
+		{"example.com/m.F[...]", "example.com/m"},
+		{"example.com/m.F[example.com/x.T]", "example.com/m"},
+		{"example.com/m.(*T[...]).M", "example.com/m"},
+	}
+	for _, test := range tests {
+		if got := funcPackage(test.name); got != test.want {
+			t.Errorf("funcPackage(%q): got %q, want %q", test.name, got,
+				test.want)
+		}
+	}
+}
+
+func TestPackageModule(t *testing.T) {
+	setInfo(t, callerInfo)
+
+	tests := []struct {
+		pkgpath string
+		want    string // module path, or empty for an error
+	}{
+		{"main", "example.com/app"},
+		{"example.com/app", "example.com/app"},
+		{"example.com/app/internal/x", "example.com/app"},
+		{"example.com/lib", "example.com/lib"},
+		{"example.com/lib/sub", "example.com/lib"},
+		{"example.com/lib/v2", "example.com/lib/v2"},
+		{"example.com/lib/v2/sub", "example.com/lib/v2"},
+		{"example.com/libx/sub", "example.com/libx"},
+		{"github.com/perillo/other", "github.com/perillo"},
+		{"github.com/perillo/data/internal/fsutil", "github.com/perillo/data"},
+		{"example.com/li", ""},
+		{"example.com/application", ""},
+	}
+	for _, test := range tests {
+		got, err := packageModule(test.pkgpath)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("packageModule(%q): got %q, want an error",
+					test.pkgpath, got)
+			}
+
+			continue
+		}
+		if err != nil {
+			t.Errorf("packageModule(%q): %v", test.pkgpath, err)
+
+			continue
+		}
+		if got != test.want {
+			t.Errorf("packageModule(%q): got %q, want %q", test.pkgpath, got,
+				test.want)
+		}
+	}
+}
+
+// setCallerModules sets the build info to callerInfo, where this package is a
+// dependency, and the default locator to a locator for the main module, this
+// package and example.com/lib, for the duration of the test t.  Each module
+// has an a.txt file, with the last element of the module path as content.  It
+// returns the root directory of the modules.
+func setCallerModules(t *testing.T) string {
+	setInfo(t, callerInfo)
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app/a.txt":  "app",
+		"data/a.txt": "data",
+		"lib/a.txt":  "lib",
+	})
+	setDefaultLocator(t, mapLocator{
+		"example.com/app":         filepath.Join(root, "app"),
+		"github.com/perillo/data": filepath.Join(root, "data"),
+		"example.com/lib":         filepath.Join(root, "lib"),
+	})
+
+	return root
+}
+
+func TestLocateCaller(t *testing.T) {
+	root := setCallerModules(t)
+
+	ld, err := LocateCaller()
+	if err != nil {
+		t.Fatalf("LocateCaller: %v", err)
+	}
+	if got, want := ld.Module().Path, "github.com/perillo/data"; got != want {
+		t.Errorf("LocateCaller: got module %q, want %q", got, want)
+	}
+	f, err := LoadCaller("a.txt")
+	if err != nil {
+		t.Fatalf("LoadCaller: %v", err)
+	}
+	if got, want := f.Path(), filepath.Join(root, "data", "a.txt"); got != want {
+		t.Errorf("LoadCaller: got path %q, want %q", got, want)
+	}
+
+	// The caller is not in an active module.
+	setInfo(t, testInfo)
+	if _, err := LocateCaller(); err == nil {
+		t.Error("LocateCaller: got no error for a package not in an active module")
+	}
+}
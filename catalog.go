@@ -0,0 +1,323 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// catalog.go source file implements the loading of gettext message catalogs
+// from module data.
+
+package data
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Catalog is a gettext message catalog.
+type Catalog struct {
+	msgs   map[string][]string // translations, keyed by context and msgid
+	plural pluralExpr          // plural form expression
+	header map[string]string   // header fields
+}
+
+// LoadCatalog loads the gettext message catalog at path from the data files
+// loaded by ld, localized for the language tags as done by NewLocaleLoader.
+// The catalog format is determined by the file extension, ".po" or ".mo".
+//
+// Fuzzy and obsolete entries in a ".po" file are ignored, as done by msgfmt,
+// with the exception of a fuzzy header.
+func LoadCatalog(ld Loader, path string, tags ...string) (*Catalog, error) {
+	f, err := NewLocaleLoader(ld, tags...).Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeCatalog(fileLocator(f), ld, f)
+}
+
+// decodeCatalog decodes the message catalog in the file f loaded by ld.
+func decodeCatalog(lc Locator, ld Loader, f File) (*Catalog, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var c *Catalog
+	switch ext := path.Ext(f.Name()); ext {
+	case ".po":
+		c, err = parsePO(rc)
+	case ".mo":
+		c, err = parseMO(rc)
+	default:
+		err = fmt.Errorf("unknown catalog format %q", ext)
+	}
+	if err != nil {
+		return nil, mkerr(lc, ld, f, "decode", err)
+	}
+
+	return c, nil
+}
+
+// Header returns the value of the field named by key in the catalog header,
+// like "Language".
+func (c *Catalog) Header(key string) string {
+	return c.header[key]
+}
+
+// Get returns the translation of msgid, or msgid if there is no translation.
+func (c *Catalog) Get(msgid string) string {
+	return c.PGet("", msgid)
+}
+
+// NGet returns the plural form of the translation of msgid for n, or msgid
+// if n is 1 and msgidPlural otherwise if there is no translation.
+func (c *Catalog) NGet(msgid, msgidPlural string, n int) string {
+	return c.NPGet("", msgid, msgidPlural, n)
+}
+
+// PGet returns the translation of msgid in the context ctx, or msgid if there
+// is no translation.
+func (c *Catalog) PGet(ctx, msgid string) string {
+	if forms := c.msgs[catalogKey(ctx, msgid)]; len(forms) > 0 {
+		return forms[0]
+	}
+
+	return msgid
+}
+
+// NPGet returns the plural form of the translation of msgid in the context
+// ctx for n, or msgid if n is 1 and msgidPlural otherwise if there is no
+// translation.
+func (c *Catalog) NPGet(ctx, msgid, msgidPlural string, n int) string {
+	forms := c.msgs[catalogKey(ctx, msgid)]
+	if i := c.plural.eval(n); i >= 0 && i < len(forms) {
+		return forms[i]
+	}
+	if n == 1 {
+		return msgid
+	}
+
+	return msgidPlural
+}
+
+// catalogKey returns the key of msgid in the context ctx, using the same
+// format of the ".mo" files.
+func catalogKey(ctx, msgid string) string {
+	if ctx == "" {
+		return msgid
+	}
+
+	return ctx + "\x04" + msgid
+}
+
+// newCatalog returns a new catalog, with the translations in msgs.  The
+// header is read from the translation of the empty msgid.
+func newCatalog(msgs map[string][]string) (*Catalog, error) {
+	c := &Catalog{
+		msgs:   msgs,
+		plural: pluralBinop{"!=", pluralN{}, pluralConst(1)},
+		header: make(map[string]string),
+	}
+	if forms := msgs[""]; len(forms) > 0 {
+		for _, line := range strings.Split(forms[0], "\n") {
+			if idx := strings.IndexByte(line, ':'); idx > 0 {
+				c.header[line[:idx]] = strings.TrimSpace(line[idx+1:])
+			}
+		}
+		delete(msgs, "")
+	}
+
+	// Plural-Forms: nplurals=3; plural=(n==1 ? 0 : n>=2 && n<=4 ? 1 : 2);
+	for _, field := range strings.Split(c.header["Plural-Forms"], ";") {
+		field = strings.TrimSpace(field)
+		if !strings.HasPrefix(field, "plural=") {
+			continue
+		}
+
+		e, err := parsePlural(field[len("plural="):])
+		if err != nil {
+			return nil, err
+		}
+		c.plural = e
+	}
+
+	return c, nil
+}
+
+// parseMO parses a catalog in the ".mo" binary format.
+func parseMO(r io.Reader) (*Catalog, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) < 20 {
+		return nil, errors.New("invalid .mo file")
+	}
+
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(buf) {
+	case 0x950412de:
+		order = binary.LittleEndian
+	case 0xde120495:
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid .mo file magic number")
+	}
+	n := order.Uint32(buf[8:])
+	origs := order.Uint32(buf[12:])
+	trans := order.Uint32(buf[16:])
+
+	// str returns the string described at offset off of a table.
+	str := func(off uint32) (string, error) {
+		if uint64(off)+8 > uint64(len(buf)) {
+			return "", errors.New("invalid .mo file string table")
+		}
+		size := order.Uint32(buf[off:])
+		start := order.Uint32(buf[off+4:])
+		if uint64(start)+uint64(size) > uint64(len(buf)) {
+			return "", errors.New("invalid .mo file string")
+		}
+
+		return string(buf[start : start+size]), nil
+	}
+
+	msgs := make(map[string][]string, n)
+	for i := uint32(0); i < n; i++ {
+		orig, err := str(origs + 8*i)
+		if err != nil {
+			return nil, err
+		}
+		tran, err := str(trans + 8*i)
+		if err != nil {
+			return nil, err
+		}
+
+		// The plural msgid follows the msgid, separated by a NUL character.
+		if idx := strings.IndexByte(orig, 0); idx >= 0 {
+			orig = orig[:idx]
+		}
+		msgs[orig] = strings.Split(tran, "\x00")
+	}
+
+	return newCatalog(msgs)
+}
+
+// poEntry is an entry of a ".po" file.
+type poEntry struct {
+	ctx    string
+	msgid  *string
+	msgstr []string
+	fuzzy  bool
+}
+
+// parsePO parses a catalog in the ".po" text format.
+func parsePO(r io.Reader) (*Catalog, error) {
+	msgs := make(map[string][]string)
+
+	var e poEntry
+	var last *string // the string continued by a string line
+	add := func() {
+		// The header is used even if it is marked as fuzzy, since msginit
+		// marks the header of new catalogs as fuzzy.
+		fuzzy := e.fuzzy && e.msgid != nil && *e.msgid != ""
+		if e.msgid != nil && !fuzzy && !untranslated(e.msgstr) {
+			msgs[catalogKey(e.ctx, *e.msgid)] = e.msgstr
+		}
+		e = poEntry{}
+		last = nil
+	}
+
+	sc := bufio.NewScanner(r)
+	for lineno := 1; sc.Scan(); lineno++ {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#,"):
+			if e.msgid != nil {
+				add()
+			}
+			e.fuzzy = strings.Contains(line, "fuzzy")
+
+			continue
+		case line[0] == '#':
+			// Comments and obsolete entries.
+			continue
+		case line[0] == '"':
+			if last == nil {
+				return nil, fmt.Errorf("line %d: unexpected string", lineno)
+			}
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			*last += s
+
+			continue
+		}
+
+		keyword := line
+		value := ""
+		if idx := strings.IndexByte(line, ' '); idx > 0 {
+			keyword = line[:idx]
+			value = strings.TrimSpace(line[idx+1:])
+		}
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+
+		switch {
+		case keyword == "msgctxt":
+			if e.msgid != nil {
+				add()
+			}
+			e.ctx = s
+			last = &e.ctx
+		case keyword == "msgid":
+			if e.msgid != nil {
+				add()
+			}
+			e.msgid = &s
+			last = e.msgid
+		case keyword == "msgid_plural":
+			// The plural msgid is not used for the lookup.
+			last = new(string)
+		case keyword == "msgstr":
+			e.msgstr = []string{s}
+			last = &e.msgstr[0]
+		case strings.HasPrefix(keyword, "msgstr["):
+			i, err := strconv.Atoi(strings.TrimSuffix(keyword[len("msgstr["):], "]"))
+			if err != nil || i != len(e.msgstr) {
+				return nil, fmt.Errorf("line %d: invalid %s", lineno, keyword)
+			}
+			e.msgstr = append(e.msgstr, s)
+			last = &e.msgstr[i]
+		default:
+			return nil, fmt.Errorf("line %d: unknown keyword %s", lineno, keyword)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	add()
+
+	return newCatalog(msgs)
+}
+
+// untranslated returns true if all the forms of a translation are empty.
+func untranslated(msgstr []string) bool {
+	for _, s := range msgstr {
+		if s != "" {
+			return false
+		}
+	}
+
+	return true
+}
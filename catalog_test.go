@@ -0,0 +1,193 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+const testPO = `# Italian translations.
+#, fuzzy
+msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+#: main.go:10
+msgid "Hello"
+msgstr "Ciao"
+
+msgid "long"
+msgstr ""
+"a long "
+"message"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Apri"
+
+msgid "Open"
+msgstr "Aprire"
+
+msgid "file"
+msgid_plural "files"
+msgstr[0] "file"
+msgstr[1] "files"
+
+#, fuzzy
+msgid "Fuzzy"
+msgstr "Sfocato"
+
+msgid "Untranslated"
+msgstr ""
+
+#~ msgid "Obsolete"
+#~ msgstr "Obsoleto"
+`
+
+// checkCatalog checks the translations in a catalog parsed from testPO or
+// from the equivalent ".mo" file.
+func checkCatalog(t *testing.T, c *Catalog) {
+	t.Helper()
+
+	if got, want := c.Header("Content-Type"), "text/plain; charset=UTF-8"; got != want {
+		t.Errorf("Header: got %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		ctx   string
+		msgid string
+		want  string
+	}{
+		{"", "Hello", "Ciao"},
+		{"", "long", "a long message"},
+		{"menu", "Open", "Apri"},
+		{"", "Open", "Aprire"},
+		{"", "Fuzzy", "Fuzzy"},
+		{"", "Untranslated", "Untranslated"},
+		{"", "Obsolete", "Obsolete"},
+		{"", "Missing", "Missing"},
+	}
+	for _, test := range tests {
+		if got := c.PGet(test.ctx, test.msgid); got != test.want {
+			t.Errorf("PGet(%q, %q): got %q, want %q", test.ctx, test.msgid,
+				got, test.want)
+		}
+	}
+
+	for n, want := range []string{"files", "file", "files"} {
+		if got := c.NGet("file", "files", n); got != want {
+			t.Errorf("NGet(%d): got %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestParsePO(t *testing.T) {
+	c, err := parsePO(strings.NewReader(testPO))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCatalog(t, c)
+}
+
+func TestParsePOErrors(t *testing.T) {
+	tests := []struct {
+		po   string
+		want string // error substring
+	}{
+		{`"orphan"`, "line 1: unexpected string"},
+		{"msgid \"a\"\nmsgstr \"b", "line 2:"},
+		{"msgid \"a\"\nmsgstr[1] \"b\"", "line 2: invalid msgstr[1]"},
+		{"msgid \"a\"\nmsgtext \"b\"", "line 2: unknown keyword msgtext"},
+		{"msgid \"\"\nmsgstr \"Plural-Forms: plural=n+;\\n\"", "plural expression"},
+	}
+	for _, test := range tests {
+		_, err := parsePO(strings.NewReader(test.po))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("parsePO(%q): got error %v, want %q", test.po, err, test.want)
+		}
+	}
+}
+
+// makeMO returns a ".mo" file with the specified original and translated
+// strings, using the byte order order.
+func makeMO(order binary.ByteOrder, msgs [][2]string) []byte {
+	const headerSize = 28
+
+	n := uint32(len(msgs))
+	origs := uint32(headerSize)
+	trans := origs + 8*n
+	off := trans + 8*n
+
+	var tables, strs bytes.Buffer
+	put := func(w *bytes.Buffer, v uint32) {
+		var b [4]byte
+		order.PutUint32(b[:], v)
+		w.Write(b[:])
+	}
+	for col := 0; col < 2; col++ {
+		for _, msg := range msgs {
+			s := msg[col]
+			put(&tables, uint32(len(s)))
+			put(&tables, off+uint32(strs.Len()))
+			strs.WriteString(s)
+			strs.WriteByte(0)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, v := range []uint32{0x950412de, 0, n, origs, trans, 0, 0} {
+		put(&buf, v)
+	}
+	buf.Write(tables.Bytes())
+	buf.Write(strs.Bytes())
+
+	return buf.Bytes()
+}
+
+// testMO are the messages of testPO, as stored in a ".mo" file.
+var testMO = [][2]string{
+	{"", "Content-Type: text/plain; charset=UTF-8\nPlural-Forms: nplurals=2; plural=(n != 1);\n"},
+	{"Hello", "Ciao"},
+	{"Open", "Aprire"},
+	{"file\x00files", "file\x00files"},
+	{"long", "a long message"},
+	{"menu\x04Open", "Apri"},
+}
+
+func TestParseMO(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		c, err := parseMO(bytes.NewReader(makeMO(order, testMO)))
+		if err != nil {
+			t.Fatalf("%v: %v", order, err)
+		}
+		checkCatalog(t, c)
+	}
+}
+
+func TestParseMOErrors(t *testing.T) {
+	mo := makeMO(binary.LittleEndian, testMO)
+	tables := 28 + 16*len(testMO)
+
+	tests := []struct {
+		desc string
+		mo   []byte
+		want string
+	}{
+		{"short", mo[:19], "invalid .mo file"},
+		{"magic", append([]byte{0, 0, 0, 0}, mo[4:]...), "invalid .mo file magic number"},
+		{"truncated tables", mo[:28+4], "invalid .mo file string table"},
+		{"truncated strings", mo[:tables+4], "invalid .mo file string"},
+	}
+	for _, test := range tests {
+		_, err := parseMO(bytes.NewReader(test.mo))
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%s: got error %v, want %q", test.desc, err, test.want)
+		}
+	}
+}
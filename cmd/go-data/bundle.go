@@ -0,0 +1,203 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/perillo/data/internal/fsutil"
+)
+
+var cmdBundle = &command{
+	name:  "bundle",
+	usage: "[-o output] [-v] executable",
+	short: "append the modules data to an executable",
+	long: `
+Bundle appends a zip archive with the data of the main module and of all the
+active modules to the named executable, so that it can be found by the
+"zip:exe" locator.
+
+The archive uses the same layout as the user data directory: the main module
+data is stored in <appname>/data, and the data of active modules is stored in
+go-data/<flatpath>/data.
+
+The modules are read from the executable build info.  The main module data is
+read from the main module in the current directory; the data of active modules
+is read from the module cache, downloading the modules if necessary.
+
+The -o flag specifies the output file.  By default, the executable is modified
+in place.  The executable must not already have an appended archive.
+
+The -v flag causes bundle to print the names of the modules with data.
+	`,
+}
+
+var (
+	bundleO = cmdBundle.flag.String("o", "", "")
+	bundleV = cmdBundle.flag.Bool("v", false, "")
+)
+
+func init() {
+	cmdBundle.run = runBundle // break init cycle
+}
+
+func runBundle(cmd *command, args []string) error {
+	if len(args) != 1 {
+		cmd.printUsage()
+		os.Exit(2)
+	}
+	exe := args[0]
+	output := *bundleO
+	if output == "" {
+		output = exe
+	}
+
+	bi, prefixes, mods, err := readModules(exe)
+	if err != nil {
+		return err
+	}
+	if r, err := zip.OpenReader(exe); err == nil {
+		r.Close()
+
+		return fmt.Errorf("%s already has an appended archive", exe)
+	}
+	dirs, err := sourceDirs(bi.Main.Path, mods)
+	if err != nil {
+		return err
+	}
+
+	// Copy the executable, and append the archive.
+	tmp, err := os.CreateTemp(filepath.Dir(output), ".go-data-bundle-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := bundle(tmp, exe, dirs, prefixes); err != nil {
+		tmp.Close()
+
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), output)
+}
+
+// bundle writes to w the executable exe, followed by a zip archive with the
+// data directory of each module in dirs, stored in the archive with the
+// corresponding prefix.
+func bundle(w *os.File, exe string, dirs, prefixes map[string]string) error {
+	r, err := os.Open(exe)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	fi, err := r.Stat()
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return err
+	}
+	if err := w.Chmod(fi.Mode().Perm()); err != nil {
+		return err
+	}
+
+	// Set the offset so that the archive can also be read by tools that do
+	// not support prepended data.
+	zw := zip.NewWriter(w)
+	zw.SetOffset(n)
+
+	// Sort the modules, so that the archive is reproducible.
+	modpaths := make([]string, 0, len(dirs))
+	for modpath := range dirs {
+		modpaths = append(modpaths, modpath)
+	}
+	sort.Strings(modpaths)
+	for _, modpath := range modpaths {
+		src := filepath.Join(dirs[modpath], "data")
+		if !fsutil.IsDir(src) {
+			continue
+		}
+		if *bundleV {
+			fmt.Fprintln(os.Stderr, modpath)
+		}
+
+		if err := addTree(zw, prefixes[modpath]+"/data", src); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// addTree adds the directory tree rooted at src to the zip archive, with the
+// specified prefix.  Symbolic links are stored as is.
+func addTree(zw *zip.Writer, prefix, src string) error {
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		mode := fi.Mode()
+		if !mode.IsDir() && !mode.IsRegular() && mode&os.ModeSymlink == 0 {
+			// Ignore other file types.
+			return nil
+		}
+
+		fh, err := zip.FileInfoHeader(fi)
+		if err != nil {
+			return err
+		}
+		fh.Name = prefix
+		if rel != "." {
+			fh.Name += "/" + filepath.ToSlash(rel)
+		}
+		if mode.IsDir() {
+			fh.Name += "/"
+		} else {
+			fh.Method = zip.Deflate
+		}
+		fw, err := zw.CreateHeader(fh)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case mode.IsDir():
+			return nil
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(fw, link)
+
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(fw, f)
+
+		return err
+	})
+}
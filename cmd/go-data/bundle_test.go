@@ -0,0 +1,183 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestDataModule(t *testing.T) {
+	tests := []struct {
+		dep  *debug.Module
+		want string // FlatPath
+	}{
+		{&debug.Module{Path: "example.com/dep", Version: "v1.0.0"},
+			"example.com.dep@v1.0.0"},
+		{&debug.Module{Path: "github.com/BurntSushi/toml", Version: "v1.0.0"},
+			"github.com.BurntSushi.toml@v1.0.0"},
+		{&debug.Module{
+			Path:    "example.com/dep",
+			Version: "v1.0.0",
+			Replace: &debug.Module{Path: "example.com/fork", Version: "v1.1.0"},
+		}, "example.com.fork@v1.1.0"},
+		{&debug.Module{
+			Path:    "example.com/dep",
+			Version: "v1.0.0",
+			Replace: &debug.Module{Path: "../dep", Version: "(devel)"},
+		}, "...dep@(devel)"},
+	}
+	for _, test := range tests {
+		if got := dataModule(test.dep).FlatPath(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.dep.Path, got, test.want)
+		}
+	}
+}
+
+func TestReadModules(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	bi, prefixes, _, err := readModules(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The test executable is named after the package, with a .test suffix.
+	if got, want := prefixes[bi.Main.Path], "go-data.test"; got != want {
+		t.Errorf("got main module prefix %q, want %q", got, want)
+	}
+
+	// An executable without build info.
+	path := filepath.Join(t.TempDir(), "app")
+	if err := os.WriteFile(path, []byte("not an executable"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := readModules(path); err == nil {
+		t.Error("got no error for an invalid executable")
+	}
+}
+
+func TestBundle(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app/data/a.txt":     "main",
+		"app/data/dir/b.txt": "b",
+		"dep/data/a.txt":     "dep",
+		"nodata/a.txt":       "no data",
+	})
+	symlinks := runtime.GOOS != "windows"
+	if symlinks {
+		err := os.Symlink("a.txt", filepath.Join(root, "app", "data", "link"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	const exedata = "\x7fELF executable"
+	exe := filepath.Join(root, "app.exe")
+	if err := os.WriteFile(exe, []byte(exedata), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	dirs := map[string]string{
+		"example.com/app":    filepath.Join(root, "app"),
+		"example.com/dep":    filepath.Join(root, "dep"),
+		"example.com/nodata": filepath.Join(root, "nodata"),
+	}
+	prefixes := map[string]string{
+		"example.com/app":    "app",
+		"example.com/dep":    "go-data/example.com.dep@v1.0.0",
+		"example.com/nodata": "go-data/example.com.nodata@v1.0.0",
+	}
+	output := filepath.Join(root, "bundled.exe")
+	w, err := os.Create(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle(w, exe, dirs, prefixes); err != nil {
+		w.Close()
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The executable is preserved, with its permissions.
+	f, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	buf := make([]byte, len(exedata))
+	if _, err := io.ReadFull(f, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != exedata {
+		t.Errorf("got executable %q, want %q", buf, exedata)
+	}
+	if runtime.GOOS != "windows" {
+		if fi, err := f.Stat(); err != nil {
+			t.Error(err)
+		} else if perm := fi.Mode().Perm(); perm != 0755 {
+			t.Errorf("got mode %v, want %v", perm, os.FileMode(0755))
+		}
+	}
+
+	r, err := zip.OpenReader(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	files := make(map[string]string)
+	for _, zf := range r.File {
+		if zf.FileInfo().IsDir() {
+			files[zf.Name] = "dir"
+
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[zf.Name] = string(content)
+	}
+
+	tests := []struct {
+		name    string
+		want    string // content, "dir" for a directory or empty if missing
+		symlink bool
+	}{
+		{"app/data/", "dir", false},
+		{"app/data/a.txt", "main", false},
+		{"app/data/dir/", "dir", false},
+		{"app/data/dir/b.txt", "b", false},
+		{"app/data/link", "a.txt", true}, // stored as is
+		{"go-data/example.com.dep@v1.0.0/data/a.txt", "dep", false},
+		{"go-data/example.com.nodata@v1.0.0/data/", "", false},
+	}
+	for _, test := range tests {
+		if test.symlink && !symlinks {
+			continue
+		}
+		got, ok := files[test.name]
+		switch {
+		case test.want == "" && ok:
+			t.Errorf("%s: got %q, want no file", test.name, got)
+		case test.want != "" && got != test.want:
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// copyTree copies the directory tree rooted at src to dst.  Symbolic links are
+// copied as is.
+func copyTree(dst, src string) error {
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch mode := fi.Mode(); {
+		case mode.IsDir():
+			// The module cache is read only, so ensure that the directory is
+			// writable.
+			return os.MkdirAll(target, 0777)
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(link, target)
+		case mode.IsRegular():
+			return copyFile(target, path, mode.Perm()|0200)
+		}
+
+		// Ignore other file types.
+		return nil
+	})
+}
+
+// copyFile copies the regular file src to dst, with the specified permission.
+func copyFile(dst, src string, perm os.FileMode) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+
+		return err
+	}
+
+	return w.Close()
+}
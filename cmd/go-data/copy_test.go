@@ -0,0 +1,95 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeFiles writes the files, by slash separated name, in the directory
+// root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"a.txt":       "a",
+		"dir/b.txt":   "b",
+		"dir/sub/c":   "c",
+		"empty/.keep": "",
+		"ro.txt":      "ro",
+	})
+	symlinks := runtime.GOOS != "windows"
+	if symlinks {
+		if err := os.Symlink("dir/b.txt", filepath.Join(src, "link")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The module cache is read only.
+	if err := os.Chmod(filepath.Join(src, "ro.txt"), 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "vendor-data", "example.com", "dep")
+	if err := copyTree(dst, src); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a.txt", "a"},
+		{"dir/b.txt", "b"},
+		{"dir/sub/c", "c"},
+		{"empty/.keep", ""},
+		{"ro.txt", "ro"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(dst, filepath.FromSlash(test.name))
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	// The copied files are writable.
+	if fi, err := os.Stat(filepath.Join(dst, "ro.txt")); err != nil {
+		t.Error(err)
+	} else if fi.Mode().Perm()&0200 == 0 {
+		t.Errorf("ro.txt: got mode %v, want writable", fi.Mode())
+	}
+
+	// Symbolic links are copied as is.
+	if symlinks {
+		link, err := os.Readlink(filepath.Join(dst, "link"))
+		if err != nil {
+			t.Error(err)
+		} else if link != "dir/b.txt" {
+			t.Errorf("link: got target %q, want %q", link, "dir/b.txt")
+		}
+	}
+}
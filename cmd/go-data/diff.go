@@ -0,0 +1,110 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/perillo/data/internal/modfile"
+)
+
+var cmdDiff = &command{
+	name:  "diff",
+	usage: "[-m module] executable [file ...]",
+	short: "show the user customizations of the modules data",
+	long: `
+Diff shows the differences between the user overrides of the data files of the
+named executable, as created by data.Eject, and the original data files, in
+unified format.  An override of a data file that does not exist is shown as a
+new file.
+
+The modules are read from the executable build info.  The original data is
+read as done by the bundle command: the main module data is read from the main
+module in the current directory, and the data of active modules is read from
+the module cache, downloading the modules if necessary.
+
+The -m flag restricts diff to the overrides of the named module.  The file
+arguments, if any, restrict diff to the overrides of the named data files.
+
+Diff uses the diff command, that must be available in PATH; otherwise diff
+reports an error.
+	`,
+}
+
+var diffM = cmdDiff.flag.String("m", "", "")
+
+func init() {
+	cmdDiff.run = runDiff // break init cycle
+}
+
+func runDiff(cmd *command, args []string) error {
+	if len(args) < 1 {
+		cmd.printUsage()
+		os.Exit(2)
+	}
+
+	bi, prefixes, mods, err := readModules(args[0])
+	if err != nil {
+		return err
+	}
+	list, err := listOverrides(prefixes, *diffM, args[1:])
+	if err != nil || len(list) == 0 {
+		return err
+	}
+
+	// Check that the diff command is available, before downloading the
+	// modules.
+	if _, err := exec.LookPath("diff"); err != nil {
+		return errors.New("the diff command is required, but it was not found in PATH")
+	}
+
+	// Read only the modules with overrides.
+	used := make(map[string]bool)
+	for _, o := range list {
+		used[o.modpath] = true
+	}
+	var deps []modfile.Module
+	for _, mod := range mods {
+		if used[mod.Path] {
+			deps = append(deps, mod)
+		}
+	}
+	dirs, err := sourceDirs(bi.Main.Path, deps)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range list {
+		orig := filepath.Join(dirs[o.modpath], "data", filepath.FromSlash(o.name))
+		if _, err := os.Stat(orig); os.IsNotExist(err) {
+			orig = os.DevNull
+		}
+		if err := diff(o.modpath+"/"+o.name, orig, o.path()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diff prints the differences between the files old and new, using label as
+// the file name.
+func diff(label, old, new string) error {
+	cmd := exec.Command("diff", "-u", "-L", "a/"+label, "-L", "b/"+label, old, new)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// The diff command exits with status 1 if the files differ.
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/perillo/data/internal/gocmd"
+)
+
+// datadirSymbol is the name of the variable storing the data root directory.
+const datadirSymbol = "github.com/perillo/data.datadir"
+
+var cmdLdflags = &command{
+	name:  "ldflags",
+	usage: "[-prefix dir | -datadir dir] [package]",
+	short: "print the linker flags for the data root directory",
+	long: `
+Ldflags prints the linker flags that set the data root directory used by the
+"fs:datadir" locator, for the main package named by the import path.  The
+default package is the one in the current directory.  The import path must not
+be a pattern matching multiple packages.
+
+The flags are intended to be used as:
+
+	go build -ldflags="$(go-data ldflags -prefix /usr)"
+
+The -prefix flag specifies the installation prefix; the data root directory is
+<prefix>/share/<appname>, where appname is the last element of the package
+import path.  The default prefix is /usr/local.
+
+The -datadir flag specifies the data root directory, and has precedence over
+the -prefix flag.
+	`,
+}
+
+var (
+	ldflagsPrefix  = cmdLdflags.flag.String("prefix", "/usr/local", "")
+	ldflagsDatadir = cmdLdflags.flag.String("datadir", "", "")
+)
+
+func init() {
+	cmdLdflags.run = runLdflags // break init cycle
+}
+
+func runLdflags(cmd *command, args []string) error {
+	if len(args) > 1 {
+		cmd.printUsage()
+		os.Exit(2)
+	}
+
+	dir := *ldflagsDatadir
+	if dir == "" {
+		pkg := "."
+		if len(args) == 1 {
+			pkg = args[0]
+		}
+		stdout, err := gocmd.Invoke("list", "-f", "{{.ImportPath}}", pkg)
+		if err != nil {
+			return err
+		}
+		// The appname is derived from a single main package, so reject
+		// patterns matching multiple packages.
+		pkgpath := string(stdout)
+		switch {
+		case pkgpath == "":
+			return fmt.Errorf("%s matches no packages", pkg)
+		case strings.Contains(pkgpath, "\n"):
+			return fmt.Errorf("%s matches multiple packages", pkg)
+		}
+		appname := path.Base(pkgpath)
+
+		dir = filepath.Join(*ldflagsPrefix, "share", appname)
+	}
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("data root directory %s is not an absolute path", dir)
+	}
+
+	flag, err := quote("-X=" + datadirSymbol + "=" + dir)
+	if err != nil {
+		return err
+	}
+	fmt.Println(flag)
+
+	return nil
+}
+
+// quote quotes s, if necessary, so that it is parsed as a single argument by
+// the go command -ldflags flag.
+func quote(s string) (string, error) {
+	switch {
+	case !strings.ContainsAny(s, " \t\n\r'\""):
+		return s, nil
+	case !strings.Contains(s, "'"):
+		return "'" + s + "'", nil
+	case !strings.Contains(s, `"`):
+		return `"` + s + `"`, nil
+	}
+
+	return "", errors.New("data root directory contains both ' and \" characters")
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string // quoted s, or empty for an error
+	}{
+		{"-X=a.b=/usr/share/app", "-X=a.b=/usr/share/app"},
+		{"-X=a.b=/opt/my app", "'-X=a.b=/opt/my app'"},
+		{"-X=a.b=/opt/my\tapp", "'-X=a.b=/opt/my\tapp'"},
+		{`-X=a.b=/opt/"app"`, `'-X=a.b=/opt/"app"'`},
+		{"-X=a.b=/opt/app's", `"-X=a.b=/opt/app's"`},
+		{"-X=a.b=/opt/app's \"data\"", ""},
+	}
+	for _, test := range tests {
+		got, err := quote(test.s)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("quote(%q): got %q, want an error", test.s, got)
+			}
+
+			continue
+		}
+		if err != nil {
+			t.Errorf("quote(%q): %v", test.s, err)
+
+			continue
+		}
+		if got != test.want {
+			t.Errorf("quote(%q): got %q, want %q", test.s, got, test.want)
+		}
+	}
+}
+
+// captureStdout returns the data written to the standard output by fn.
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stdout := os.Stdout
+	os.Stdout = f
+	err = fn()
+	os.Stdout = stdout
+
+	buf, rerr := os.ReadFile(f.Name())
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+
+	return string(buf), err
+}
+
+func TestLdflagsDatadir(t *testing.T) {
+	abs := filepath.Join(t.TempDir(), "share", "my app")
+	tests := []struct {
+		datadir string
+		want    string // printed flags, or empty for an error
+	}{
+		{abs, "'-X=" + datadirSymbol + "=" + abs + "'"},
+		{"share/app", ""},
+	}
+	for _, test := range tests {
+		*ldflagsDatadir = test.datadir
+		got, err := captureStdout(t, func() error {
+			return runLdflags(cmdLdflags, nil)
+		})
+		*ldflagsDatadir = ""
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%s: got %q, want an error", test.datadir, got)
+			}
+
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.datadir, err)
+
+			continue
+		}
+		if got = strings.TrimSpace(got); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.datadir, got, test.want)
+		}
+	}
+}
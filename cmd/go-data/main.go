@@ -0,0 +1,128 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Go-data is a tool for managing module data.
+//
+// Usage:
+//
+//	go-data <command> [arguments]
+//
+// The commands are:
+//
+//	bundle      append the modules data to an executable
+//	diff        show the user customizations of the modules data
+//	ldflags     print the linker flags for the data root directory
+//	reset       discard the user customizations of the modules data
+//	vendor      copy the data of vendored modules
+//
+// Use "go-data help <command>" for more information about a command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// command represents a go-data command.
+type command struct {
+	// run runs the command.  The args are the arguments after the command
+	// name.
+	run func(cmd *command, args []string) error
+
+	name  string       // the command name
+	usage string       // the one line usage message, without the command name
+	short string       // the short description shown by go-data help
+	long  string       // the long description shown by go-data help <command>
+	flag  flag.FlagSet // flags specific to this command
+}
+
+// commands lists the available commands.
+var commands = []*command{
+	cmdBundle,
+	cmdDiff,
+	cmdLdflags,
+	cmdReset,
+	cmdVendor,
+}
+
+func (cmd *command) printUsage() {
+	fmt.Fprintf(os.Stderr, "usage: go-data %s %s\n", cmd.name, cmd.usage)
+	fmt.Fprintf(os.Stderr, "Run 'go-data help %s' for details.\n", cmd.name)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Go-data is a tool for managing module data.")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "\tgo-data <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "The commands are:")
+	fmt.Fprintln(os.Stderr)
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-11s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, `Use "go-data help <command>" for more information about a command.`)
+}
+
+// help implements the help command.
+func help(args []string) {
+	if len(args) == 0 {
+		usage()
+
+		return
+	}
+
+	name := args[0]
+	for _, cmd := range commands {
+		if cmd.name == name {
+			fmt.Fprintf(os.Stderr, "usage: go-data %s %s\n\n", cmd.name, cmd.usage)
+			fmt.Fprintln(os.Stderr, strings.TrimSpace(cmd.long))
+
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "go-data help %s: unknown command\n", name)
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := args[0]
+	if name == "help" {
+		help(args[1:])
+
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		cmd.flag.Usage = cmd.printUsage
+		cmd.flag.Parse(args[1:])
+		if err := cmd.run(cmd, cmd.flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "go-data %s: %v\n", cmd.name, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "go-data %s: unknown command\n", name)
+	fmt.Fprintln(os.Stderr, "Run 'go-data help' for usage.")
+	os.Exit(2)
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+
+	"github.com/perillo/data"
+	"github.com/perillo/data/internal/gocmd"
+	"github.com/perillo/data/internal/modfile"
+)
+
+// readModules reads the build info of the executable exe.  It returns the
+// build info, the prefix of the data of each module, and the active modules.
+//
+// The prefixes use the same layout as the user data directory: the prefix of
+// the main module is <appname>, and the prefix of active modules is
+// go-data/<flatpath>.
+func readModules(exe string) (*buildinfo.BuildInfo, map[string]string, []modfile.Module, error) {
+	bi, err := buildinfo.ReadFile(exe)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	mods := make([]modfile.Module, len(bi.Deps))
+	prefixes := map[string]string{
+		bi.Main.Path: path.Base(bi.Path),
+	}
+	for i, dep := range bi.Deps {
+		mods[i] = modfile.Module{
+			Path:    dep.Path,
+			Version: dep.Version,
+		}
+		if dep.Replace != nil {
+			mods[i].Replace = dep.Replace.Path
+			if dep.Replace.Version != "(devel)" {
+				// The module is not replaced by a directory.
+				mods[i].ReplaceVersion = dep.Replace.Version
+			}
+		}
+
+		prefixes[dep.Path] = "go-data/" + dataModule(dep).FlatPath()
+	}
+
+	return bi, prefixes, mods, nil
+}
+
+// sourceDirs returns a map from the path of the main module, named by
+// mainpath, and of each module in mods to its root directory.  The main module
+// must be in the current directory.
+func sourceDirs(mainpath string, mods []modfile.Module) (map[string]string, error) {
+	root, err := gocmd.ModRoot()
+	if err != nil {
+		return nil, err
+	}
+	if modpath, err := modfile.ModulePath(filepath.Join(root, "go.mod")); err != nil {
+		return nil, err
+	} else if modpath != mainpath {
+		return nil, fmt.Errorf("main module %s is not in the current directory",
+			mainpath)
+	}
+
+	dirs, err := moddirs(root, mods)
+	if err != nil {
+		return nil, err
+	}
+	dirs[mainpath] = root
+
+	return dirs, nil
+}
+
+// moddirs returns a map from the path of each module in mods to its root
+// directory, downloading the modules to the module cache if necessary.
+func moddirs(root string, mods []modfile.Module) (map[string]string, error) {
+	dirs := make(map[string]string, len(mods))
+
+	var (
+		queries []string
+		paths   = make(map[string]string) // query to module path
+	)
+	for _, mod := range mods {
+		path, version := mod.Path, mod.Version
+		if mod.Replace != "" {
+			path, version = mod.Replace, mod.ReplaceVersion
+		}
+		if version == "" {
+			// The module is replaced by a directory.
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(root, path)
+			}
+			dirs[mod.Path] = path
+
+			continue
+		}
+
+		query := path + "@" + version
+		queries = append(queries, query)
+		paths[query] = mod.Path
+	}
+	if len(queries) == 0 {
+		return dirs, nil
+	}
+
+	args := append([]string{"download", "-json"}, queries...)
+	stdout, err := gocmd.Invoke("mod", args...)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(stdout))
+	for {
+		var m struct {
+			Path    string
+			Version string
+			Error   string
+			Dir     string
+		}
+		if err := dec.Decode(&m); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if m.Error != "" {
+			return nil, fmt.Errorf("%s@%s: %s", m.Path, m.Version, m.Error)
+		}
+		dirs[paths[m.Path+"@"+m.Version]] = m.Dir
+	}
+
+	return dirs, nil
+}
+
+// dataModule returns the module used by the data package at run time for the
+// dependency dep, to compute FlatPath.  As done by the data package, a
+// replaced module is reported as its replacement.
+func dataModule(dep *debug.Module) *data.Module {
+	if dep.Replace != nil {
+		dep = dep.Replace
+	}
+	mod := &data.Module{
+		Path:    dep.Path,
+		Version: dep.Version,
+	}
+
+	return mod
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/perillo/data/internal/fsutil"
+)
+
+// override is a user override of a module data file.
+type override struct {
+	modpath string // the module path
+	name    string // the data file name, relative to the data directory
+	root    string // absolute path to the overrides directory
+}
+
+// path returns the absolute path to the override.
+func (o *override) path() string {
+	return filepath.Join(o.root, filepath.FromSlash(o.name))
+}
+
+// overridesDir returns the overrides directory for the module data stored
+// with the specified prefix, as done by data.OverridesDir.
+func overridesDir(prefix string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(prefix), "overrides"), nil
+}
+
+// listOverrides returns the user overrides of the modules in prefixes, sorted
+// by module path and name.  If modpath is not empty, only the overrides of the
+// named module are returned.  If names is not empty, only the overrides of the
+// named data files are returned.
+func listOverrides(prefixes map[string]string, modpath string, names []string) ([]override, error) {
+	if modpath != "" {
+		if _, ok := prefixes[modpath]; !ok {
+			return nil, fmt.Errorf("module %s is not an active module", modpath)
+		}
+	}
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		selected[path.Clean(name)] = true
+	}
+
+	var list []override
+	for mod, prefix := range prefixes {
+		if modpath != "" && mod != modpath {
+			continue
+		}
+		root, err := overridesDir(prefix)
+		if err != nil {
+			return nil, err
+		}
+		if !fsutil.IsDir(root) {
+			continue
+		}
+
+		err = filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(rel)
+			if len(selected) > 0 && !selected[name] {
+				return nil
+			}
+			list = append(list, override{mod, name, root})
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].modpath != list[j].modpath {
+			return list[i].modpath < list[j].modpath
+		}
+
+		return list[i].name < list[j].name
+	})
+
+	return list, nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setConfigDir sets the user configuration directory to a temporary
+// directory, for the duration of the test t.
+func setConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir) // unix
+	t.Setenv("HOME", dir)            // darwin
+	t.Setenv("AppData", dir)         // windows
+	t.Setenv("home", dir)            // plan9
+}
+
+func TestListOverrides(t *testing.T) {
+	setConfigDir(t)
+
+	prefixes := map[string]string{
+		"example.com/app": "app",
+		"example.com/dep": "go-data/example.com.dep@v1.0.0",
+		"example.com/new": "go-data/example.com.new@v1.0.0",
+	}
+	for prefix, files := range map[string]map[string]string{
+		"app": {
+			"a.txt":     "a",
+			"dir/b.txt": "b",
+		},
+		"go-data/example.com.dep@v1.0.0": {
+			"a.txt": "dep",
+		},
+	} {
+		root, err := overridesDir(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, root, files)
+	}
+
+	tests := []struct {
+		desc    string
+		modpath string
+		names   []string
+		want    []string // module path and name, or nil for an error
+	}{
+		{"all", "", nil, []string{
+			"example.com/app a.txt",
+			"example.com/app dir/b.txt",
+			"example.com/dep a.txt",
+		}},
+		{"module", "example.com/app", nil, []string{
+			"example.com/app a.txt",
+			"example.com/app dir/b.txt",
+		}},
+		{"names", "", []string{"a.txt", "./dir/b.txt"}, []string{
+			"example.com/app a.txt",
+			"example.com/app dir/b.txt",
+			"example.com/dep a.txt",
+		}},
+		{"module and names", "example.com/dep", []string{"a.txt"}, []string{
+			"example.com/dep a.txt",
+		}},
+		{"no overrides", "example.com/new", nil, []string{}},
+		{"inactive module", "example.com/other", nil, nil},
+	}
+	for _, test := range tests {
+		list, err := listOverrides(prefixes, test.modpath, test.names)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("%s: got no error", test.desc)
+			}
+
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.desc, err)
+
+			continue
+		}
+		got := []string{}
+		for _, o := range list {
+			got = append(got, o.modpath+" "+o.name)
+			if _, err := os.Stat(o.path()); err != nil {
+				t.Errorf("%s: %v", test.desc, err)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestRemoveEmpty(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a/keep.txt": "",
+	})
+	dir := filepath.Join(root, "a", "b", "c")
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	removeEmpty(dir, root)
+
+	tests := []struct {
+		name   string
+		exists bool
+	}{
+		{"a/b/c", false},
+		{"a/b", false},
+		{"a", true},
+		{".", true},
+	}
+	for _, test := range tests {
+		_, err := os.Stat(filepath.Join(root, filepath.FromSlash(test.name)))
+		if exists := err == nil; exists != test.exists {
+			t.Errorf("%s: got exists %t, want %t", test.name, exists,
+				test.exists)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if _, err := exec.LookPath("diff"); err != nil {
+		t.Skip("the diff command is not available")
+	}
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"old.txt": "a\n",
+		"new.txt": "b\n",
+	})
+	tests := []struct {
+		desc string
+		old  string
+		want []string // lines in the output
+	}{
+		{"modified", filepath.Join(root, "old.txt"), []string{
+			"--- a/example.com/app/a.txt", "+++ b/example.com/app/a.txt",
+			"-a", "+b",
+		}},
+		{"new", os.DevNull, []string{"+b"}},
+		{"unchanged", filepath.Join(root, "new.txt"), nil},
+	}
+	for _, test := range tests {
+		got, err := captureStdout(t, func() error {
+			return diff("example.com/app/a.txt", test.old,
+				filepath.Join(root, "new.txt"))
+		})
+		if err != nil {
+			t.Errorf("%s: %v", test.desc, err)
+
+			continue
+		}
+		if test.want == nil && got != "" {
+			t.Errorf("%s: got %q, want no output", test.desc, got)
+		}
+		lines := strings.Split(got, "\n")
+		for _, want := range test.want {
+			found := false
+			for _, line := range lines {
+				if line == want {
+					found = true
+
+					break
+				}
+			}
+			if !found {
+				t.Errorf("%s: got %q, want line %q", test.desc, got, want)
+			}
+		}
+	}
+
+	// A missing file is an error, reported by diff on the standard error.
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	stderr := os.Stderr
+	os.Stderr = null
+	defer func() {
+		os.Stderr = stderr
+	}()
+	_, err = captureStdout(t, func() error {
+		return diff("a.txt", filepath.Join(root, "missing.txt"),
+			filepath.Join(root, "new.txt"))
+	})
+	if err == nil {
+		t.Error("missing file: got no error")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var cmdReset = &command{
+	name:  "reset",
+	usage: "[-m module] [-n] [-v] executable [file ...]",
+	short: "discard the user customizations of the modules data",
+	long: `
+Reset removes the user overrides of the data files of the named executable, as
+created by data.Eject, so that the original data files are used again.  Empty
+directories in the overrides directory are removed.
+
+The modules are read from the executable build info.
+
+The -m flag restricts reset to the overrides of the named module.  The file
+arguments, if any, restrict reset to the overrides of the named data files.
+
+The -n flag causes reset to print the overrides that would be removed, without
+removing them.
+
+The -v flag causes reset to print the overrides as they are removed.
+	`,
+}
+
+var (
+	resetM = cmdReset.flag.String("m", "", "")
+	resetN = cmdReset.flag.Bool("n", false, "")
+	resetV = cmdReset.flag.Bool("v", false, "")
+)
+
+func init() {
+	cmdReset.run = runReset // break init cycle
+}
+
+func runReset(cmd *command, args []string) error {
+	if len(args) < 1 {
+		cmd.printUsage()
+		os.Exit(2)
+	}
+
+	_, prefixes, _, err := readModules(args[0])
+	if err != nil {
+		return err
+	}
+	list, err := listOverrides(prefixes, *resetM, args[1:])
+	if err != nil {
+		return err
+	}
+
+	for _, o := range list {
+		if *resetN || *resetV {
+			fmt.Println(o.path())
+		}
+		if *resetN {
+			continue
+		}
+
+		if err := os.Remove(o.path()); err != nil {
+			return err
+		}
+		removeEmpty(filepath.Dir(o.path()), o.root)
+	}
+
+	return nil
+}
+
+// removeEmpty removes the directory dir and its parents, while they are empty,
+// stopping at root.
+func removeEmpty(dir, root string) {
+	for dir != root && len(dir) > len(root) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/perillo/data"
+	"github.com/perillo/data/internal/fsutil"
+	"github.com/perillo/data/internal/gocmd"
+	"github.com/perillo/data/internal/modfile"
+)
+
+var cmdVendor = &command{
+	name:  "vendor",
+	usage: "[-v]",
+	short: "copy the data of vendored modules",
+	long: `
+Vendor copies the data directory of each module listed in vendor/modules.txt
+into the vendor-data directory, in the main module root directory, so that it
+can be found by the "fs:vendor" locator.
+
+The data of each module is stored in a subdirectory named by the module path.
+The vendor-data directory is removed before copying the data, so that it
+contains only the data of the currently vendored modules.
+
+The go mod vendor command does not copy the data directory of a module, since
+it is not a package, and removes any extra file in the vendor directory.  For
+this reason, vendor should be run after go mod vendor.
+
+The -v flag causes vendor to print the names of the modules with data.
+	`,
+}
+
+var vendorV = cmdVendor.flag.Bool("v", false, "")
+
+func init() {
+	cmdVendor.run = runVendor // break init cycle
+}
+
+func runVendor(cmd *command, args []string) error {
+	if len(args) > 0 {
+		cmd.printUsage()
+		os.Exit(2)
+	}
+
+	root, err := gocmd.ModRoot()
+	if err != nil {
+		return err
+	}
+	mods, err := modfile.ParseVendor(filepath.Join(root, "vendor", "modules.txt"))
+	if err != nil {
+		return err
+	}
+	dirs, err := moddirs(root, mods)
+	if err != nil {
+		return err
+	}
+
+	dst := filepath.Join(root, data.VendorDataDir)
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	for _, mod := range mods {
+		src := filepath.Join(dirs[mod.Path], "data")
+		if !fsutil.IsDir(src) {
+			continue
+		}
+		if *vendorV {
+			fmt.Fprintln(os.Stderr, mod.Path)
+		}
+
+		err := copyTree(filepath.Join(dst, filepath.FromSlash(mod.Path)), src)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// context.go source file implements the context-scoped locators.
+
+package data
+
+import "context"
+
+// locatorKey is the context key for the locator.
+type locatorKey struct{}
+
+// WithLocator returns a copy of ctx with the locator l, so that the functions
+// using ctx, like LoadContext, use l instead of the default locator.  This
+// allows different request handlers or tests to safely use different
+// locators.
+func WithLocator(ctx context.Context, l Locator) context.Context {
+	if l == nil {
+		panic("data: WithLocator called with a nil locator")
+	}
+
+	return context.WithValue(ctx, locatorKey{}, l)
+}
+
+// ContextLocator returns the locator set in ctx by WithLocator, or the default
+// locator.
+func ContextLocator(ctx context.Context) Locator {
+	if l, ok := ctx.Value(locatorKey{}).(Locator); ok {
+		return l
+	}
+
+	return DefaultLocator()
+}
+
+// LocateContext is like Locate, but it uses the locator from ctx, as returned
+// by ContextLocator.
+func LocateContext(ctx context.Context) (Loader, error) {
+	return ContextLocator(ctx).Locate(mainPath())
+}
+
+// LoadContext is like Load, but it uses the locator from ctx, as returned by
+// ContextLocator.
+func LoadContext(ctx context.Context, path string) (File, error) {
+	l, err := LocateContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Load(path)
+}
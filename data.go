@@ -9,11 +9,16 @@
 //
 // The data may be provided by the main module or one of the active modules,
 // and it will be accessed by the main module.
+//
+// Compressed data files, like name.gz, are not decompressed by default: the
+// loader returned by a locator must be wrapped with NewDecompressingLoader.
 package data
 
 import (
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
 )
 
 // Locator is responsible for finding how to load module data.
@@ -56,27 +61,113 @@ type File interface {
 	Open() (io.ReadCloser, error)
 }
 
-// DefaultLocator is the default locator.  It is automatically set to, in
+// DefaultLocator returns the default locator.  It is automatically set to, in
 // order:
 //
 //  1. If build info is not available, the "null" locator
-//  2. If the main module version is "(devel)", the "fs:gopath" locator
-//  3. The "fs:user" locator, if the main module is in $GODATA
-//  4. The "fs:modcache" locator, if the main module is in the module cache
-//  5. The "null" locator
-var DefaultLocator Locator
+//  2. The "fs:datadir" locator, if the data root directory has been set at
+//     build time and the main module is in the data root directory
+//  3. The "zip:exe" locator, if the main module is in the zip archive appended
+//     to the executable
+//  4. If the main module version is "(devel)", the "fs:workspace" locator, if
+//     the main module is in the go.work workspace, the "fs:vendor" locator, if
+//     the go command uses the main module vendor directory, otherwise the
+//     "fs:gopath" locator
+//  5. The "fs:user" locator, if the main module is in $GODATA
+//  6. The "fs:exe" locator, if the main module is relative to the executable
+//  7. The "fs:system" locator, if the main module is in $XDG_DATA_DIRS
+//  8. The "fs:modcache" locator, if the main module is in the module cache
+//  9. The "zip:modcache" locator, if the main module zip file is in the module
+//     cache
+//  10. The "proxy" locator, if downloads from the module proxy are enabled
+//     and the main module can be downloaded from the module proxy
+//  11. The "null" locator
+//
+// Since the "proxy" locator accesses the network, downloads from the module
+// proxy are disabled by default.  They are enabled at build time with:
+//
+//	-ldflags=-X=github.com/perillo/data.proxymode=on
+//
+// When enabled, the modules not found by one of the locators 5 to 9, like a
+// dependency missing from $GODATA, are downloaded from the module proxy.  The
+// other locators are not tried for these modules.
+//
+// The default locator can be changed with SetDefaultLocator.
+func DefaultLocator() Locator {
+	return defaultLoc.Load().(locatorValue).l
+}
+
+// SetDefaultLocator sets the default locator to l.  It is safe to call
+// SetDefaultLocator concurrently with the functions using the default
+// locator, like Load.
+//
+// To use a different locator only in a scope, like a request handler or a
+// test, use WithLocator.
+func SetDefaultLocator(l Locator) {
+	if l == nil {
+		panic("data: SetDefaultLocator called with a nil locator")
+	}
+	defaultLoc.Store(locatorValue{l})
+}
+
+// defaultLoc stores the default locator.
+var defaultLoc atomic.Value
+
+// locatorValue wraps a Locator, since the values stored in an atomic.Value
+// must have the same concrete type.
+type locatorValue struct {
+	l Locator
+}
 
 // locators is a map with available locators.
 var locators map[string]Locator
 
+// lazyLocators is a map with available locators that are created on first
+// use, since they run the go command and they are only used in development
+// mode.
+var lazyLocators = map[string]*lazyLocator{
+	"fs:vendor":    {fn: newVendorLocator},
+	"fs:workspace": {fn: newWorkspaceLocator},
+}
+
+// lazyLocator is a locator created on first use.
+type lazyLocator struct {
+	fn   func() Locator // the locator constructor
+	once sync.Once
+	l    Locator
+}
+
+// get returns the locator, creating it on the first call.
+func (ll *lazyLocator) get() Locator {
+	ll.once.Do(func() {
+		ll.l = ll.fn()
+	})
+
+	return ll.l
+}
+
 // Locate returns the loader for the main module, using the default locator.
 func Locate() (Loader, error) {
+	return DefaultLocator().Locate(mainPath())
+}
+
+// LocateModule returns the loader for the module named by modpath, using the
+// default locator.  modpath may be the path of the main module or of one of
+// the active modules.
+func LocateModule(modpath string) (Loader, error) {
+	return DefaultLocator().Locate(modpath)
+}
+
+// mainPath returns the path of the main module, or an empty string if build
+// info is not available.
+func mainPath() string {
+	info := loadInfo()
 	if info == nil {
-		// The DefaultLocator is "null", if build info is not available.
-		return DefaultLocator.Locate("")
+		// The default locator is "null", if build info is not available.
+		return ""
 	}
 
-	return DefaultLocator.Locate(info.Main.Path)
+	return info.Main.Path
 }
 
 // LocatorByName returns the locator by its name, or nil if not available.
@@ -85,14 +176,24 @@ func Locate() (Loader, error) {
 // usable on the host system, e.g. if the GOPATH environment variable is not
 // defined for the "fs:gopath" locator.
 //
-// Supported locators are "fs:gopath", "fs:modcache" and "fs:user".
+// The locators are created when the package is initialized, using the build
+// info of the running binary, with the exception of "fs:vendor" and
+// "fs:workspace" that are created on first use, and only when the main module
+// version is "(devel)".  They are not affected by datatest.SetModules.
+//
+// Supported locators are "fs:datadir", "fs:exe", "fs:gopath", "fs:modcache",
+// "fs:override", "fs:system", "fs:user", "fs:vendor", "fs:workspace", "proxy",
+// "zip:exe" and "zip:modcache".  The "fs:override" locator locates the user
+// overrides in OverridesDir, and it is used by NewOverrideLoader.
 func LocatorByName(name string) Locator {
-	l, ok := locators[name]
-	if !ok {
-		return nil
+	if l, ok := locators[name]; ok {
+		return l
+	}
+	if ll, ok := lazyLocators[name]; ok {
+		return ll.get()
 	}
 
-	return l
+	return nil
 }
 
 // Load returns the file associated at path for the main module, using the
@@ -108,22 +209,42 @@ func Load(path string) (File, error) {
 	return l.Load(path)
 }
 
+// LoadModule returns the file associated at path for the module named by
+// modpath, using the default locator.
+//
+// path must be a relative path, without the "data/" prefix.
+func LoadModule(modpath, path string) (File, error) {
+	l, err := LocateModule(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Load(path)
+}
+
 func init() {
 	// Ensure the global info variable is initialized before everything else.
 	bi, ok := readBuildInfo()
 	if !ok {
 		return
 	}
-	info = bi
+	storeInfo(bi)
 
 	// Initialize the supported locators.
 	locators = map[string]Locator{
-		"fs:gopath":   newGopathLocator(),
-		"fs:modcache": newModcacheLocator(),
-		"fs:user":     newUserLocator(),
+		"fs:datadir":   newDatadirLocator(),
+		"fs:exe":       NewExeLocator(),
+		"fs:gopath":    newGopathLocator(),
+		"fs:modcache":  newModcacheLocator(),
+		"fs:override":  &overrideLocator{},
+		"fs:system":    newSystemLocator(),
+		"fs:user":      newUserLocator(),
+		"proxy":        proxyLoc,
+		"zip:exe":      newExezipLocator(),
+		"zip:modcache": newModzipLocator(),
 	}
 }
 
 func init() {
-	DefaultLocator = defaultLocator()
+	SetDefaultLocator(defaultLocator())
 }
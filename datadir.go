@@ -0,0 +1,112 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// datadir.go source file implements the "fs:datadir" locator.
+
+package data
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/perillo/data/internal/fsutil"
+)
+
+// datadir is the absolute path to the data root directory, set at build time
+// with:
+//
+//	-ldflags=-X=github.com/perillo/data.datadir=<dir>
+//
+// The "go-data ldflags" command prints the flags for a given prefix.
+var datadir string
+
+// datadirLocator implements the "fs:datadir" locator that locates a module in
+// the data root directory set at build time.
+type datadirLocator struct {
+	path string
+}
+
+// newDatadirLocator returns a new "fs:datadir" locator, for modules in the
+// data root directory set at build time.
+//
+// newDatadirLocator returns the "null" locator if the data root directory is
+// not set or the main module is not stored in the data root directory.
+func newDatadirLocator() Locator {
+	info := loadInfo()
+
+	if datadir == "" {
+		return &nullLocator{
+			err: errors.New("data root directory is not set"),
+		}
+	}
+	if !filepath.IsAbs(datadir) {
+		return &nullLocator{
+			err: fmt.Errorf("data root directory %s is not an absolute path",
+				datadir),
+		}
+	}
+
+	l := &datadirLocator{
+		path: datadir,
+	}
+
+	// Check if the main module is in the data root directory.
+	if _, err := l.locate(info.Main.Path); err != nil {
+		return &nullLocator{
+			err: fmt.Errorf("main module %s is not in the data root directory",
+				&info.Main),
+		}
+	}
+
+	return l
+}
+
+// Locate implements the Locator interface.
+func (l *datadirLocator) Locate(modpath string) (Loader, error) {
+	ld, err := l.locate(modpath)
+	if err != nil {
+		return nil, mkerr(l, err)
+	}
+
+	return ld, nil
+}
+
+func (l *datadirLocator) locate(modpath string) (Loader, error) {
+	info := loadInfo()
+
+	// Find module in build info.
+	mod, err := find(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The main module is special, and the data is stored in the data root
+	// directory.  Active modules are stored in go-data, with the fully
+	// versioned path flattened.
+	dirpath := l.path
+	if modpath != info.Main.Path {
+		dirpath = filepath.Join(l.path, "go-data", mod.FlatPath())
+	}
+
+	if !fsutil.IsDir(dirpath) {
+		return nil, fmt.Errorf("module %s is not in the data root directory",
+			modpath)
+	}
+
+	// It is responsibility of Loader to report an error if the data
+	// directory does not exists.
+	ld := &fsLoader{
+		lc:   l,
+		mod:  mod,
+		root: filepath.Join(dirpath, "data"),
+	}
+
+	return ld, nil
+}
+
+// Name implements the Locator interface.
+func (l datadirLocator) Name() string {
+	return "fs:datadir"
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// setDatadir sets the data root directory to dir, for the duration of the
+// test t.
+func setDatadir(t *testing.T, dir string) {
+	old := datadir
+	datadir = dir
+	t.Cleanup(func() {
+		datadir = old
+	})
+}
+
+func TestDatadirLocator(t *testing.T) {
+	setInfo(t, testInfo)
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"data/a.txt": "main",
+		"go-data/example.com.dep@v1.0.0/data/a.txt": "dep",
+	})
+	setDatadir(t, root)
+
+	testLocate(t, newDatadirLocator(), "fs:datadir", []locateTest{
+		{"example.com/app", "main"},
+		{"example.com/dep", "dep"},
+		{"example.com/other", ""},
+	})
+
+	tests := []struct {
+		desc string
+		dir  string
+	}{
+		{"not set", ""},
+		{"relative", "data"},
+		{"missing", filepath.Join(root, "missing")},
+	}
+	for _, test := range tests {
+		setDatadir(t, test.dir)
+		if name := newDatadirLocator().Name(); name != "null" {
+			t.Errorf("%s: got locator %q, want %q", test.desc, name, "null")
+		}
+	}
+}
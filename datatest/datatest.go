@@ -0,0 +1,84 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package datatest implements support for testing code that loads module data.
+//
+// A test can replace the default locator with an in-memory locator, and the
+// module table read from the build info with a fake one:
+//
+//	func TestConfig(t *testing.T) {
+//		datatest.SetModules(t, data.Module{Path: "example.com/app"})
+//		datatest.SetDefaultLocator(t, datatest.NewLocator(map[string]fs.FS{
+//			"example.com/app": datatest.Files{
+//				"config.json": `{"debug": true}`,
+//			},
+//		}))
+//
+//		// data.Load("config.json") loads the in-memory file.
+//	}
+//
+// The functions changing the state of the data package must not be used by
+// parallel tests.  Parallel tests can use a different locator with
+// data.WithLocator and data.LoadContext.
+package datatest
+
+import (
+	"testing"
+
+	"github.com/perillo/data"
+	"github.com/perillo/data/internal/testhook"
+)
+
+// SetDefaultLocator sets the default locator to l for the duration of the
+// test t.  The previous locator is restored when the test and all its
+// subtests complete.
+func SetDefaultLocator(t testing.TB, l data.Locator) {
+	t.Helper()
+
+	old := data.DefaultLocator()
+	data.SetDefaultLocator(l)
+	t.Cleanup(func() {
+		data.SetDefaultLocator(old)
+	})
+}
+
+// SetModules sets the module table used by the data package, normally read
+// from the build info, for the duration of the test t.  main is the main
+// module, and deps are the module dependencies.  The main package path is the
+// main module path, so that data.AppName returns its last element.
+//
+// The module table is used by the functions that find the module of a
+// package, like data.Locate and data.ForPackage, and by the functions that
+// find the per-user directories of a module, like data.WritableDir.  The
+// locators created before the call to SetModules are not affected, including
+// the ones returned by data.LocatorByName and the default locator set when the
+// data package is initialized; use SetDefaultLocator to replace the latter.
+func SetModules(t testing.TB, main data.Module, deps ...data.Module) {
+	t.Helper()
+
+	hdeps := make([]testhook.Module, len(deps))
+	for i := range deps {
+		hdeps[i] = toHook(&deps[i])
+	}
+	restore := testhook.SetBuildInfo(main.Path, toHook(&main), hdeps)
+	t.Cleanup(restore)
+}
+
+// toHook converts the module from data.Module to testhook.Module.
+func toHook(m *data.Module) testhook.Module {
+	mod := testhook.Module{
+		Path:    m.Path,
+		Version: m.Version,
+		Sum:     m.Sum,
+	}
+	if m.Replace != nil {
+		mod.Replace = &testhook.Module{
+			Path:    m.Replace.Path,
+			Version: m.Replace.Version,
+			Sum:     m.Replace.Sum,
+		}
+	}
+
+	return mod
+}
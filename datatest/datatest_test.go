@@ -0,0 +1,100 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package datatest_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/perillo/data"
+	"github.com/perillo/data/datatest"
+)
+
+// load returns the content of the data file at path for the module named by
+// modpath, using the default locator.
+func load(modpath, path string) (string, error) {
+	f, err := data.LoadModule(modpath, path)
+	if err != nil {
+		return "", err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	buf, err := io.ReadAll(rc)
+
+	return string(buf), err
+}
+
+func TestSetModules(t *testing.T) {
+	old := data.AppName()
+
+	t.Run("fake", func(t *testing.T) {
+		datatest.SetModules(t,
+			data.Module{Path: "example.com/app"},
+			data.Module{Path: "example.com/lib", Version: "v1.0.0"},
+		)
+		datatest.SetDefaultLocator(t, datatest.NewLocator(map[string]fs.FS{
+			"example.com/app": datatest.Files{
+				"config.json": `{"debug": true}`,
+			},
+			"example.com/lib": datatest.Files{
+				"tmpl/index.html": "<html>",
+			},
+		}))
+
+		if got, want := data.AppName(), "app"; got != want {
+			t.Errorf("AppName: got %q, want %q", got, want)
+		}
+
+		f, err := data.Load("config.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(buf), `{"debug": true}`; got != want {
+			t.Errorf("Load: got %q, want %q", got, want)
+		}
+
+		got, err := load("example.com/lib", "tmpl/index.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "<html>"; got != want {
+			t.Errorf("LoadModule: got %q, want %q", got, want)
+		}
+
+		_, err = load("example.com/app", "missing.json")
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("missing file: got error %v, want %v", err, os.ErrNotExist)
+		}
+		_, err = load("example.com/other", "config.json")
+		var e *data.Error
+		if !errors.As(err, &e) || e.Locator.Name() != "mem" {
+			t.Errorf("inactive module: got error %v, want a mem locator error", err)
+		}
+	})
+
+	// The build info and the default locator are restored.
+	if got := data.AppName(); got != old {
+		t.Errorf("AppName after cleanup: got %q, want %q", got, old)
+	}
+	if name := data.DefaultLocator().Name(); name == "mem" {
+		t.Errorf("DefaultLocator after cleanup: got %q", name)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// locator.go source file implements the in-memory "mem" locator.
+
+package datatest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"testing/fstest"
+	"time"
+
+	"github.com/perillo/data"
+)
+
+// Files is an in-memory file system, mapping the slash separated names of
+// regular files to their content.  It is a simpler alternative to
+// fstest.MapFS, for the data of a module.
+type Files map[string]string
+
+// Open implements the fs.FS interface.
+func (files Files) Open(name string) (fs.File, error) {
+	return files.MapFS().Open(name)
+}
+
+// MapFS returns the files as an fstest.MapFS.
+func (files Files) MapFS() fstest.MapFS {
+	fsys := make(fstest.MapFS, len(files))
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{
+			Data:    []byte(content),
+			Mode:    0444,
+			ModTime: time.Unix(0, 0),
+		}
+	}
+
+	return fsys
+}
+
+// locator implements the "mem" locator, that locates module data in memory.
+type locator struct {
+	mods map[string]fs.FS
+}
+
+// NewLocator returns a new "mem" locator, for the modules in mods.  mods maps
+// the path of each module to its data, as an fs.FS rooted at the data
+// directory, like Files or fstest.MapFS.
+//
+// The loaders returned by the locator implement fs.FS, so that they are
+// supported by data.Glob.  The Path method of the loaded files returns an
+// empty string.
+func NewLocator(mods map[string]fs.FS) data.Locator {
+	l := &locator{
+		mods: mods,
+	}
+
+	return l
+}
+
+// Locate implements the data.Locator interface.
+func (l *locator) Locate(modpath string) (data.Loader, error) {
+	fsys, ok := l.mods[modpath]
+	if !ok {
+		return nil, &data.Error{
+			Locator: l,
+			Err:     fmt.Errorf("module %s is not in the locator", modpath),
+		}
+	}
+
+	ld := &loader{
+		lc:   l,
+		mod:  &data.Module{Path: modpath},
+		fsys: fsys,
+	}
+
+	return ld, nil
+}
+
+// Name implements the data.Locator interface.
+func (l *locator) Name() string {
+	return "mem"
+}
+
+// loader implements a data.Loader that loads module data from an fs.FS.
+type loader struct {
+	lc   data.Locator
+	mod  *data.Module
+	fsys fs.FS
+}
+
+// Load implements the data.Loader interface.
+func (l *loader) Load(name string) (data.File, error) {
+	if !fs.ValidPath(path.Clean(name)) {
+		return nil, &data.Error{
+			Locator: l.lc,
+			Loader:  l,
+			Err:     fmt.Errorf("path %s is not a relative path", name),
+		}
+	}
+
+	file := &file{
+		ld:   l,
+		name: name,
+	}
+
+	return file, nil
+}
+
+// Module implements the data.Loader interface.
+func (l *loader) Module() *data.Module {
+	return l.mod
+}
+
+// Open implements the fs.FS interface.
+func (l *loader) Open(name string) (fs.File, error) {
+	return l.fsys.Open(name)
+}
+
+// file represents an in-memory file.
+type file struct {
+	ld   *loader
+	name string // the requested name
+}
+
+// Name implements the data.File interface.
+func (f *file) Name() string {
+	return f.name
+}
+
+// Path implements the data.File interface.  The file is stored in memory, so
+// Path always returns an empty string.
+func (f *file) Path() string {
+	return ""
+}
+
+// Lstat implements the data.File interface.
+func (f *file) Lstat() (os.FileInfo, error) {
+	fi, err := fs.Stat(f.ld.fsys, path.Clean(f.name))
+	if err != nil {
+		return nil, f.error("lstat", err)
+	}
+
+	return fi, nil
+}
+
+// Open implements the data.File interface.
+func (f *file) Open() (io.ReadCloser, error) {
+	rf, err := f.ld.fsys.Open(path.Clean(f.name))
+	if err != nil {
+		return nil, f.error("open", err)
+	}
+
+	fi, err := rf.Stat()
+	if err != nil {
+		rf.Close()
+
+		return nil, f.error("open", err)
+	}
+	if fi.IsDir() {
+		rf.Close()
+
+		return nil, f.error("open", errors.New("is a directory"))
+	}
+
+	return rf, nil
+}
+
+// error returns a *data.Error for the operation op.
+func (f *file) error(op string, err error) error {
+	// The file name is already reported by data.Error.
+	var perr *fs.PathError
+	if errors.As(err, &perr) {
+		err = perr.Err
+	}
+
+	return &data.Error{
+		Locator: f.ld.lc,
+		Loader:  f.ld,
+		File:    f,
+		Op:      op,
+		Err:     err,
+	}
+}
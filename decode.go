@@ -0,0 +1,122 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// decode.go source file implements the decoding of data files.
+
+package data
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+)
+
+// Decoder decodes the data read from r and stores the result in the value
+// pointed to by v.
+type Decoder func(r io.Reader, v interface{}) error
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		".json": func(r io.Reader, v interface{}) error {
+			return json.NewDecoder(r).Decode(v)
+		},
+		".xml": func(r io.Reader, v interface{}) error {
+			return xml.NewDecoder(r).Decode(v)
+		},
+		".gob": func(r io.Reader, v interface{}) error {
+			return gob.NewDecoder(r).Decode(v)
+		},
+	}
+)
+
+// RegisterDecoder registers the decoder for the files with the specified
+// extension, including the leading dot, like ".toml".  The decoders for
+// ".json", ".xml" and ".gob" are registered by default.
+//
+// Registering a decoder for an extension that is already registered replaces
+// it.
+func RegisterDecoder(ext string, fn Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+
+	decoders[ext] = fn
+}
+
+// DecodeFile loads the file associated at path for the main module, using the
+// default locator, and decodes it with the decoder registered for the file
+// extension.
+//
+// Errors are reported as *Error values, with Op set to "decode" when the
+// content of the file is invalid.
+func DecodeFile(path string, v interface{}) error {
+	return decodeFile(path, v, "")
+}
+
+// LoadJSON loads the file associated at path for the main module, using the
+// default locator, and decodes it as JSON, regardless of the file extension.
+func LoadJSON(path string, v interface{}) error {
+	return decodeFile(path, v, ".json")
+}
+
+// LoadXML loads the file associated at path for the main module, using the
+// default locator, and decodes it as XML, regardless of the file extension.
+func LoadXML(path string, v interface{}) error {
+	return decodeFile(path, v, ".xml")
+}
+
+// LoadGob loads the file associated at path for the main module, using the
+// default locator, and decodes it as gob, regardless of the file extension.
+func LoadGob(path string, v interface{}) error {
+	return decodeFile(path, v, ".gob")
+}
+
+// decodeFile decodes the file at path with the decoder registered for ext.
+// If ext is empty, the file extension is used.
+func decodeFile(file string, v interface{}, ext string) error {
+	lc := DefaultLocator()
+	ld, err := lc.Locate(mainPath())
+	if err != nil {
+		return err
+	}
+	f, err := ld.Load(file)
+	if err != nil {
+		return err
+	}
+
+	return decode(lc, ld, f, v, ext)
+}
+
+// decode decodes the file f loaded by ld, using the decoder registered for
+// ext.  If ext is empty, the file extension is used.
+func decode(lc Locator, ld Loader, f File, v interface{}, ext string) error {
+	if ext == "" {
+		ext = path.Ext(f.Name())
+	}
+
+	decodersMu.RLock()
+	fn, ok := decoders[ext]
+	decodersMu.RUnlock()
+	if !ok {
+		err := fmt.Errorf("no decoder for extension %q", ext)
+
+		return mkerr(lc, ld, f, "decode", err)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err := fn(rc, v); err != nil {
+		return mkerr(lc, ld, f, "decode", err)
+	}
+
+	return nil
+}
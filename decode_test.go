@@ -0,0 +1,145 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// decodeValue is the value decoded by the decoder tests.
+type decodeValue struct {
+	Name string `json:"name" xml:"name"`
+}
+
+// registerDecoder registers the decoder for ext, for the duration of the test
+// t.
+func registerDecoder(t *testing.T, ext string, fn Decoder) {
+	decodersMu.RLock()
+	old, ok := decoders[ext]
+	decodersMu.RUnlock()
+
+	RegisterDecoder(ext, fn)
+	t.Cleanup(func() {
+		decodersMu.Lock()
+		defer decodersMu.Unlock()
+
+		if ok {
+			decoders[ext] = old
+		} else {
+			delete(decoders, ext)
+		}
+	})
+}
+
+// decodeKV decodes a name=value line.
+func decodeKV(name string) Decoder {
+	return func(r io.Reader, v interface{}) error {
+		buf, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		kv := strings.SplitN(strings.TrimSpace(string(buf)), "=", 2)
+		if len(kv) != 2 || kv[0] != name {
+			return errors.New("invalid key")
+		}
+		v.(*decodeValue).Name = kv[1]
+
+		return nil
+	}
+}
+
+func TestDecodeFile(t *testing.T) {
+	setInfo(t, testInfo)
+
+	var gobData bytes.Buffer
+	if err := gob.NewEncoder(&gobData).Encode(decodeValue{"gob"}); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.json":   `{"name": "json"}`,
+		"a.xml":    `<v><name>xml</name></v>`,
+		"a.gob":    gobData.String(),
+		"a.kv":     "name=kv",
+		"a.conf":   `{"name": "conf"}`,
+		"a.txt":    "text",
+		"bad.json": `{"name":`,
+		"bad.kv":   "other=kv",
+	})
+	setDefaultLocator(t, mapLocator{
+		testInfo.Main.Path: root,
+	})
+
+	// A registered decoder can be replaced.
+	registerDecoder(t, ".kv", decodeKV("other"))
+	registerDecoder(t, ".kv", decodeKV("name"))
+
+	tests := []struct {
+		desc string
+		fn   func(path string, v interface{}) error
+		path string
+		want string // decoded name, or error substring
+		op   string // *Error Op, for an error
+	}{
+		{"json", DecodeFile, "a.json", "json", ""},
+		{"xml", DecodeFile, "a.xml", "xml", ""},
+		{"gob", DecodeFile, "a.gob", "gob", ""},
+		{"registered", DecodeFile, "a.kv", "kv", ""},
+		{"LoadJSON", LoadJSON, "a.conf", "conf", ""},
+		{"LoadXML", LoadXML, "a.xml", "xml", ""},
+		{"LoadGob", LoadGob, "a.gob", "gob", ""},
+		{"no decoder", DecodeFile, "a.txt", `no decoder for extension ".txt"`,
+			"decode"},
+		{"no extension", DecodeFile, "a", `no decoder for extension ""`,
+			"decode"},
+		{"invalid json", DecodeFile, "bad.json", "unexpected EOF", "decode"},
+		{"invalid registered", DecodeFile, "bad.kv", "invalid key", "decode"},
+		{"LoadJSON invalid", LoadJSON, "a.txt", "invalid character", "decode"},
+	}
+	for _, test := range tests {
+		var v decodeValue
+		err := test.fn(test.path, &v)
+		if test.op == "" {
+			if err != nil {
+				t.Errorf("%s: %v", test.desc, err)
+			} else if v.Name != test.want {
+				t.Errorf("%s: got %q, want %q", test.desc, v.Name, test.want)
+			}
+
+			continue
+		}
+
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Errorf("%s: got error %v, want *Error", test.desc, err)
+
+			continue
+		}
+		if e.Op != test.op {
+			t.Errorf("%s: got op %q, want %q", test.desc, e.Op, test.op)
+		}
+		if e.Locator == nil || e.Locator.Name() != "map" {
+			t.Errorf("%s: got locator %v, want %q", test.desc, e.Locator, "map")
+		}
+		if e.File == nil || e.File.Name() != test.path {
+			t.Errorf("%s: got file %v, want %q", test.desc, e.File, test.path)
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want %q", test.desc, err, test.want)
+		}
+	}
+
+	// A missing file is reported as not existing.
+	var v decodeValue
+	if err := DecodeFile("missing.json", &v); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: got error %v, want %v", err, os.ErrNotExist)
+	}
+}
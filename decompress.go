@@ -0,0 +1,364 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// decompress.go source file implements a Loader that transparently
+// decompresses the data files.
+
+package data
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Decompressor returns a reader that decompresses the data read from r.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// decompressor is a registered Decompressor.
+type decompressor struct {
+	ext string
+	fn  Decompressor
+}
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = []decompressor{
+		{".gz", func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		}},
+		{".bz2", func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(bzip2.NewReader(r)), nil
+		}},
+	}
+)
+
+// RegisterDecompressor registers the decompressor for the files with the
+// specified extension, including the leading dot, like ".zst".  The
+// decompressors for ".gz" and ".bz2" are registered by default.
+//
+// The decompressors are tried in registration order.  Registering a
+// decompressor for an extension that is already registered replaces it.
+func RegisterDecompressor(ext string, fn Decompressor) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+
+	for i, d := range decompressors {
+		if d.ext == ext {
+			decompressors[i].fn = fn
+
+			return
+		}
+	}
+	decompressors = append(decompressors, decompressor{ext, fn})
+}
+
+// decompressLoader implements a Loader that transparently decompresses the
+// data files.
+type decompressLoader struct {
+	ld Loader
+}
+
+// NewDecompressingLoader returns a Loader that wraps ld, so that when a data
+// file does not exist, but a compressed file with the same name and the
+// extension of a registered decompressor exists, like name.gz, the
+// compressed file is used and transparently decompressed.
+//
+// The files returned by the loader report the uncompressed view, where
+// possible: Lstat reports the uncompressed name and size, and Path returns an
+// empty string, since the content of the file at the path is compressed.
+// Lstat does not read the compressed file; the uncompressed size is computed
+// the first time the Size method is called, by decompressing the file.
+//
+// The decompressing loader is not used by default, since it may access the
+// data files more than once for each Load.  It must be explicitly requested:
+//
+//	ld, err := data.Locate()
+//	if err != nil {
+//		return err
+//	}
+//	ld = data.NewDecompressingLoader(ld)
+func NewDecompressingLoader(ld Loader) Loader {
+	if _, ok := ld.(*decompressLoader); ok {
+		return ld
+	}
+
+	return &decompressLoader{ld: ld}
+}
+
+// Load implements the Loader interface.
+func (l *decompressLoader) Load(path string) (File, error) {
+	f, err := l.ld.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The compressed file is resolved lazily, so that Load does not access
+	// the data files.
+	file := &decompressFile{
+		ld: l.ld,
+		f:  f,
+	}
+
+	return file, nil
+}
+
+// Module implements the Loader interface.
+func (l *decompressLoader) Module() *Module {
+	return l.ld.Module()
+}
+
+// glob implements the globber interface.  The names of the compressed files
+// are reported without the compression extension, when the uncompressed name
+// matches pattern.  A name is reported only once, even if both the compressed
+// and uncompressed files exist.
+func (l *decompressLoader) glob(pattern string) ([]string, error) {
+	decompressorsMu.RLock()
+	exts := make([]string, len(decompressors))
+	for i, d := range decompressors {
+		exts[i] = d.ext
+	}
+	decompressorsMu.RUnlock()
+
+	m := newMatcher(pattern)
+	seen := make(map[string]bool)
+	for _, p := range append([]string{""}, exts...) {
+		names, err := Glob(l.ld, pattern+p)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			name = logicalName(name, pattern, exts)
+			if !seen[name] {
+				seen[name] = true
+				m.add(name)
+			}
+		}
+	}
+
+	return m.result(), nil
+}
+
+// logicalName returns the name of the compressed file named by name, without
+// the compression extension, if the resulting name matches pattern.
+// Otherwise it returns name.
+func logicalName(name, pattern string, exts []string) string {
+	for _, ext := range exts {
+		if !strings.HasSuffix(name, ext) || len(name) == len(ext) {
+			continue
+		}
+		lname := strings.TrimSuffix(name, ext)
+		if ok, _ := path.Match(pattern, lname); ok {
+			return lname
+		}
+	}
+
+	return name
+}
+
+// decompressFile represents a file that may be stored compressed.
+type decompressFile struct {
+	ld Loader
+	f  File // the file with the requested name
+
+	once sync.Once
+	lc   Locator      // the locator reported by the loader errors, or nil
+	cf   File         // the compressed file, nil if not compressed
+	fn   Decompressor // the decompressor for cf
+	fi   os.FileInfo  // the information about cf, with the uncompressed view
+}
+
+// Name implements the File interface.
+func (f *decompressFile) Name() string {
+	return f.f.Name()
+}
+
+// Path implements the File interface.  It returns an empty string if the file
+// is compressed.
+func (f *decompressFile) Path() string {
+	f.once.Do(f.resolve)
+	if f.cf != nil {
+		return ""
+	}
+
+	return f.f.Path()
+}
+
+// Lstat implements the File interface.
+func (f *decompressFile) Lstat() (os.FileInfo, error) {
+	f.once.Do(f.resolve)
+	if f.cf == nil {
+		return f.f.Lstat()
+	}
+
+	return f.fi, nil
+}
+
+// Open implements the File interface.
+func (f *decompressFile) Open() (io.ReadCloser, error) {
+	f.once.Do(f.resolve)
+	if f.cf == nil {
+		return f.f.Open()
+	}
+
+	return f.open()
+}
+
+// OpenRandom implements the RandomFile interface.  The content of a compressed
+// file is read in memory.
+func (f *decompressFile) OpenRandom() (RandomReader, error) {
+	f.once.Do(f.resolve)
+	if f.cf == nil {
+		return OpenRandom(f.f)
+	}
+
+	return readRandom(f)
+}
+
+// unwrap returns the underlying file, or nil if the file is compressed.
+func (f *decompressFile) unwrap() File {
+	f.once.Do(f.resolve)
+	if f.cf != nil {
+		return nil
+	}
+
+	return f.f
+}
+
+// open opens the compressed file, returning a decompressing reader.
+func (f *decompressFile) open() (io.ReadCloser, error) {
+	rc, err := f.cf.Open()
+	if err != nil {
+		return nil, err
+	}
+	zr, err := f.fn(rc)
+	if err != nil {
+		rc.Close()
+
+		return nil, mkerr(f.lc, f.ld, f, "open", err)
+	}
+
+	return &decompressReader{zr, rc}, nil
+}
+
+// resolve searches the compressed file, if the requested file does not exist.
+func (f *decompressFile) resolve() {
+	_, err := f.f.Lstat()
+	if !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	f.lc = locatorOf(err)
+
+	decompressorsMu.RLock()
+	list := make([]decompressor, len(decompressors))
+	copy(list, decompressors)
+	decompressorsMu.RUnlock()
+
+	for _, d := range list {
+		cf, err := f.ld.Load(f.f.Name() + d.ext)
+		if err != nil {
+			continue
+		}
+		fi, err := cf.Lstat()
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+
+		f.cf = cf
+		f.fn = d.fn
+		f.fi = f.stat(fi, d.ext)
+
+		return
+	}
+}
+
+// stat returns the information about the compressed file, with the
+// uncompressed name.  The uncompressed size is computed lazily.
+func (f *decompressFile) stat(fi os.FileInfo, ext string) os.FileInfo {
+	ufi := &uncompressedInfo{
+		FileInfo: fi,
+		name:     fi.Name()[:len(fi.Name())-len(ext)],
+		f:        f,
+	}
+
+	return ufi
+}
+
+// size returns the uncompressed size of the compressed file, by decompressing
+// it.  The size stored in the trailer of a gzip file is not used, since it is
+// the size modulo 4 GiB of the last member only.
+func (f *decompressFile) size() (int64, error) {
+	rc, err := f.open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	size, err := io.Copy(io.Discard, rc)
+	if err != nil {
+		return 0, mkerr(f.lc, f.ld, f, "lstat", err)
+	}
+
+	return size, nil
+}
+
+// locatorOf returns the locator reported by err, or nil.
+func locatorOf(err error) Locator {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Locator
+	}
+
+	return nil
+}
+
+// decompressReader is an io.ReadCloser that closes both the decompressor and
+// the compressed file.
+type decompressReader struct {
+	io.ReadCloser           // the decompressor
+	rc            io.Closer // the compressed file
+}
+
+// Close implements the io.Closer interface.
+func (r *decompressReader) Close() error {
+	err := r.ReadCloser.Close()
+	if err := r.rc.Close(); err != nil {
+		return err
+	}
+
+	return err
+}
+
+// uncompressedInfo is an os.FileInfo for a compressed file, with the
+// uncompressed name and size.
+type uncompressedInfo struct {
+	os.FileInfo
+	name string
+	f    *decompressFile
+
+	once sync.Once
+	size int64
+}
+
+func (fi *uncompressedInfo) Name() string { return fi.name }
+
+// Size returns the uncompressed size, decompressing the file the first time
+// it is called.  If the file can not be decompressed, Size returns the
+// compressed size.
+func (fi *uncompressedInfo) Size() int64 {
+	fi.once.Do(func() {
+		size, err := fi.f.size()
+		if err != nil {
+			size = fi.FileInfo.Size()
+		}
+		fi.size = size
+	})
+
+	return fi.size
+}
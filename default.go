@@ -12,6 +12,8 @@ import (
 // defaultLocator returns the default locator as specified in the
 // documentation.
 func defaultLocator() Locator {
+	info := loadInfo()
+
 	// Check the build info to determine if this executable was installed with
 	// go get.
 	if info == nil {
@@ -20,19 +22,70 @@ func defaultLocator() Locator {
 		}
 	}
 
+	// The data root directory set at build time and the data appended to the
+	// executable have precedence.
+	if l := LocatorByName("fs:datadir"); l.Name() == "fs:datadir" {
+		return l
+	}
+	if l := LocatorByName("zip:exe"); l.Name() == "zip:exe" {
+		return l
+	}
+
 	if info.Main.Version == "(devel)" {
-		// Development mode, try to use the "fs:gopath" locator.
+		// Development mode, try to use the "fs:workspace" and "fs:vendor"
+		// locators, falling back to the "fs:gopath" locator.
+		if l := LocatorByName("fs:workspace"); l.Name() == "fs:workspace" {
+			return l
+		}
+		if l := LocatorByName("fs:vendor"); l.Name() == "fs:vendor" {
+			return l
+		}
+
 		return LocatorByName("fs:gopath")
 	}
 
-	// Installed mode.  Determine if the data is in the user data directory or
-	// in the module cache.
+	// Installed mode.  If downloads from the module proxy are enabled at
+	// build time, download the modules that are not found locally.
+	l := installedLocator()
+	if proxymode != "on" {
+		return l
+	}
+	if l.Name() == "null" {
+		// As a last resort, download the main module from the module proxy.
+		if proxyLoc.usable(info.Main.Path) {
+			return proxyLoc
+		}
+
+		return l
+	}
+
+	return &fallbackLocator{
+		l:     l,
+		proxy: proxyLoc,
+	}
+}
+
+// installedLocator returns the locator to use in installed mode, without
+// accessing the network.
+func installedLocator() Locator {
+	// Determine if the data is in the user data directory, relative to the
+	// executable, in the system data directories or in the module cache,
+	// extracted or as a zip file.
 	if l := LocatorByName("fs:user"); l.Name() == "fs:user" {
 		return l
 	}
+	if l := LocatorByName("fs:exe"); l.Name() == "fs:exe" {
+		return l
+	}
+	if l := LocatorByName("fs:system"); l.Name() == "fs:system" {
+		return l
+	}
 	if l := LocatorByName("fs:modcache"); l.Name() == "fs:modcache" {
 		return l
 	}
+	if l := LocatorByName("zip:modcache"); l.Name() == "zip:modcache" {
+		return l
+	}
 
 	// Fallback to the "null" locator.
 	return &nullLocator{
@@ -40,9 +93,24 @@ func defaultLocator() Locator {
 	}
 }
 
+// develMode returns an error if the main module reported by info is not in
+// development mode, that is if its version is not "(devel)".
+func develMode(info *buildInfo) error {
+	if info == nil {
+		return errors.New("build info is not available")
+	}
+	if info.Main.Version != "(devel)" {
+		return errors.New("main module is not in development mode")
+	}
+
+	return nil
+}
+
 // find finds the module named by modpath in the build info.  find assumes that
 // info is not nil.
 func find(modpath string) (*Module, error) {
+	info := loadInfo()
+
 	// TODO(mperillo): Use a module cache in find.
 	if modpath == info.Main.Path {
 		return &info.Main, nil
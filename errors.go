@@ -11,17 +11,22 @@ import "fmt"
 // Error records an error during a data operation.
 //
 // Error is returned by Locator.Locate, Loader.Load, File.Lstat and File.Open.
+// Locator is nil when the locator is not known, like for a loader not
+// returned by a locator of this package.
 type Error struct {
 	Locator Locator
 	Loader  Loader
 	File    File
-	Op      string // the file operation used ("lstat" or "open")
+	Op      string // the file operation ("lstat", "open", "decode" or "eject")
 	Err     error  // the underlying error
 }
 
 // Error implements the error interface.
 func (e *Error) Error() string {
-	msg := "data: " + e.Locator.Name() + ": "
+	msg := "data: "
+	if e.Locator != nil {
+		msg = msg + e.Locator.Name() + ": "
+	}
 	if e.Loader == nil {
 		return msg + e.Err.Error()
 	}
@@ -40,7 +45,7 @@ func (e *Error) Unwrap() error {
 }
 
 // mkerr builds an error value from its arguments.  It will panic if there are
-// no arguments.
+// no arguments.  nil arguments are ignored.
 //
 // The mkerr function has been inspired by upspin.io/errors.  See
 // https://commandcenter.blogspot.com/2017/12/error-handling-in-upspin.html.
@@ -52,6 +57,8 @@ func mkerr(args ...interface{}) error {
 	e := &Error{}
 	for _, arg := range args {
 		switch arg := arg.(type) {
+		case nil:
+			continue
 		case Locator:
 			e.Locator = arg
 		case Loader:
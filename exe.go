@@ -0,0 +1,165 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// exe.go source file implements the "fs:exe" locator.
+
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/perillo/data/internal/fsutil"
+)
+
+// exeLayouts are the default layouts used by the "fs:exe" locator.
+var exeLayouts = []string{
+	"../share/$APPNAME",
+	".",
+}
+
+// exeLocator implements the "fs:exe" locator that locates a module in a
+// directory relative to the executable.
+type exeLocator struct {
+	pathList []string // absolute paths of the layouts
+}
+
+// NewExeLocator returns a new "fs:exe" locator, for modules stored in a
+// directory relative to the running executable, as used by relocatable
+// installs.
+//
+// Each layout is a directory, relative to the directory containing the
+// executable, with symbolic links resolved.  $APPNAME is expanded to the
+// value returned by AppName.  The main module data is stored in
+// <layout>/data, and the data of active modules is stored in
+// <layout>/go-data/<FlatPath>/data.  Layouts are searched in order, and the
+// first one containing the data directory is used.
+//
+// If layouts is empty, the default layouts "../share/$APPNAME" and "." are
+// used, as for the "fs:exe" locator returned by LocatorByName.
+//
+// NewExeLocator returns the "null" locator if the executable path is not
+// available or the main module data is not stored in any of the layouts.
+func NewExeLocator(layouts ...string) Locator {
+	info := loadInfo()
+	if info == nil {
+		return &nullLocator{
+			err: errors.New("build info is not available"),
+		}
+	}
+	if len(layouts) == 0 {
+		layouts = exeLayouts
+	}
+
+	exedir, err := exedir()
+	if err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	l := &exeLocator{
+		pathList: make([]string, len(layouts)),
+	}
+	for i, layout := range layouts {
+		layout = os.Expand(layout, func(key string) string {
+			if key == "APPNAME" {
+				return AppName()
+			}
+
+			return ""
+		})
+		if !filepath.IsAbs(layout) {
+			layout = filepath.Join(exedir, layout)
+		}
+		l.pathList[i] = layout
+	}
+
+	// Check if the main module is relative to the executable.
+	if _, err := l.locate(info.Main.Path); err != nil {
+		return &nullLocator{
+			err: fmt.Errorf("main module %s is not relative to the executable",
+				&info.Main),
+		}
+	}
+
+	return l
+}
+
+// Locate implements the Locator interface.
+func (l *exeLocator) Locate(modpath string) (Loader, error) {
+	ld, err := l.locate(modpath)
+	if err != nil {
+		return nil, mkerr(l, err)
+	}
+
+	return ld, nil
+}
+
+func (l *exeLocator) locate(modpath string) (Loader, error) {
+	info := loadInfo()
+
+	// Find module in build info.
+	mod, err := find(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, root := range l.pathList {
+		dirpath := root
+		if modpath != info.Main.Path {
+			// Active modules are stored in <layout>/go-data, with the fully
+			// versioned path flattened.
+			dirpath = filepath.Join(root, "go-data", mod.FlatPath())
+		}
+
+		// A layout may be a generic directory, like the one containing the
+		// executable, so check the data directory instead.
+		datapath := filepath.Join(dirpath, "data")
+		if fsutil.IsDir(datapath) {
+			ld := &fsLoader{
+				lc:   l,
+				mod:  mod,
+				root: datapath,
+			}
+
+			return ld, nil
+		}
+	}
+
+	return nil, fmt.Errorf("module %s is not relative to the executable", modpath)
+}
+
+// Name implements the Locator interface.
+func (l exeLocator) Name() string {
+	return "fs:exe"
+}
+
+// exedir returns the directory containing the running executable, with
+// symbolic links resolved.
+func exedir() (string, error) {
+	path, err := exepath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Dir(path), nil
+}
+
+// exepath returns the path to the running executable, with symbolic links
+// resolved.
+func exepath() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("executable path is not available: %v", err)
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("executable path is not available: %v", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testInfo is the build info used by the locator tests.
+var testInfo = buildInfo{
+	Path: "example.com/app/cmd/app",
+	Main: Module{Path: "example.com/app", Version: "(devel)"},
+	Deps: []Module{
+		{Path: "example.com/dep", Version: "v1.0.0"},
+	},
+}
+
+// setInfo sets the build info to bi, for the duration of the test t.
+func setInfo(t *testing.T, bi buildInfo) {
+	old := loadInfo()
+	storeInfo(&bi)
+	t.Cleanup(func() {
+		storeInfo(old)
+	})
+}
+
+// writeFiles writes the files, by slash separated name, in the directory
+// root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExeLocator(t *testing.T) {
+	setInfo(t, testInfo)
+
+	exedir, err := exedir()
+	if err != nil {
+		t.Skip(err)
+	}
+	tmpdir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, tmpdir, map[string]string{
+		"share/app/data/a.txt":                                "main",
+		"share/app/go-data/example.com.dep@v1.0.0/data/b.txt": "dep",
+		"empty/README":                                        "",
+	})
+	rel, err := filepath.Rel(exedir, tmpdir)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		desc    string
+		layouts []string
+		want    string // locator name
+	}{
+		{"absolute", []string{filepath.Join(tmpdir, "share/app")}, "fs:exe"},
+		{"relative", []string{filepath.Join(rel, "share/app")}, "fs:exe"},
+		{"appname", []string{filepath.Join(tmpdir, "share/$APPNAME")}, "fs:exe"},
+		{"second layout", []string{filepath.Join(tmpdir, "empty"),
+			filepath.Join(tmpdir, "share/app")}, "fs:exe"},
+		{"missing data", []string{filepath.Join(tmpdir, "empty")}, "null"},
+		{"default layouts", nil, "null"},
+	}
+	for _, test := range tests {
+		lc := NewExeLocator(test.layouts...)
+		if name := lc.Name(); name != test.want {
+			t.Errorf("%s: got locator %q, want %q", test.desc, name, test.want)
+
+			continue
+		}
+		if test.want == "null" {
+			continue
+		}
+
+		for modpath, file := range map[string]struct{ name, want string }{
+			"example.com/app": {"a.txt", "main"},
+			"example.com/dep": {"b.txt", "dep"},
+		} {
+			ld, err := lc.Locate(modpath)
+			if err != nil {
+				t.Errorf("%s: %v", test.desc, err)
+
+				continue
+			}
+			got, err := readAll(ld, file.name)
+			if err != nil {
+				t.Errorf("%s: %v", test.desc, err)
+
+				continue
+			}
+			if got != file.want {
+				t.Errorf("%s: %s: got %q, want %q", test.desc, modpath, got,
+					file.want)
+			}
+		}
+	}
+}
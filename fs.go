@@ -12,6 +12,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/perillo/data/internal/fsutil"
 )
 
 // fsLoader implements a Loader that loads module data from the filesystem.
@@ -35,7 +37,7 @@ func (l *fsLoader) load(path string) (File, error) {
 	if filepath.IsAbs(path) {
 		return nil, fmt.Errorf("path %s is not a relative path", path)
 	}
-	if !isDir(l.root) {
+	if !fsutil.IsDir(l.root) {
 		return nil, fmt.Errorf("module %v does not have data", l.mod)
 	}
 
@@ -55,6 +57,56 @@ func (l *fsLoader) Module() *Module {
 	return l.mod
 }
 
+// glob implements the globber interface.
+func (l *fsLoader) glob(pattern string) ([]string, error) {
+	if !fsutil.IsDir(l.root) {
+		err := fmt.Errorf("module %v does not have data", l.mod)
+
+		return nil, mkerr(l.lc, l, err)
+	}
+
+	m := newMatcher(pattern)
+	err := filepath.Walk(l.root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// The unreadable subdirectories are skipped.
+			if path != l.root && fi != nil && fi.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return err
+		}
+		if path == l.root {
+			return nil
+		}
+
+		name, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		if fi.IsDir() {
+			if m.skip(name) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		// Symbolic links are followed, since they are followed by Open.
+		if fi, err := os.Stat(path); err != nil || !fi.Mode().IsRegular() {
+			return nil
+		}
+		m.add(name)
+
+		return nil
+	})
+	if err != nil {
+		return nil, mkerr(l.lc, l, err)
+	}
+
+	return m.result(), nil
+}
+
 // fsFile represents a file on the local filesystem.
 type fsFile struct {
 	lc   Locator
@@ -98,12 +150,26 @@ func (f *fsFile) Open() (io.ReadCloser, error) {
 	return rc, nil
 }
 
-// isDir returns true if path exists and it is a directory.
-func isDir(path string) bool {
-	fi, err := os.Stat(path)
+// OpenRandom implements the RandomFile interface.
+func (f *fsFile) OpenRandom() (RandomReader, error) {
+	r, err := openFile(f.Path())
 	if err != nil {
-		return false
+		return nil, mkerr(f.lc, f.ld, f, "open", err)
+	}
+
+	return r, nil
+}
+
+// openMapped returns a RandomReader for the file memory mapped in read only
+// mode, falling back to OpenRandom if memory mapping is not supported.
+func (f *fsFile) openMapped() (RandomReader, error) {
+	r, err := mmap(f.Path())
+	if err == errNoMmap {
+		return f.OpenRandom()
+	}
+	if err != nil {
+		return nil, mkerr(f.lc, f.ld, f, "open", err)
 	}
 
-	return fi.IsDir()
+	return r, nil
 }
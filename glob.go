@@ -0,0 +1,118 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// glob.go source file implements the listing of data files matching a
+// pattern.
+
+package data
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// globber is implemented by the loaders that can list their data files.
+type globber interface {
+	// glob returns the names of the data files matching pattern.
+	glob(pattern string) ([]string, error)
+}
+
+// Glob returns the names of the data files loaded by ld matching pattern, in
+// lexical order.  The pattern syntax is the same as in path.Match, and the
+// names are relative to the data directory, like "tmpl/index.html".
+// Directories are never matched.
+//
+// Glob is supported by loaders for the local filesystem and archives, by the
+// loaders wrapping them, like the ones returned by NewDecompressingLoader and
+// NewCachingLoader, and by loaders implementing fs.FS, with the data directory
+// as the root.
+func Glob(ld Loader, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, mkerr(ld, err)
+	}
+
+	switch g := ld.(type) {
+	case globber:
+		return g.glob(pattern)
+	case fs.FS:
+		names, err := globFS(g, pattern)
+		if err != nil {
+			return nil, mkerr(ld, err)
+		}
+
+		return names, nil
+	}
+	err := errors.New("loader does not support listing files")
+
+	return nil, mkerr(ld, err)
+}
+
+// globFS returns the names of the files in fsys matching pattern.
+func globFS(fsys fs.FS, pattern string) ([]string, error) {
+	m := newMatcher(pattern)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if d.IsDir() {
+			if m.skip(name) {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+		m.add(name)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.result(), nil
+}
+
+// matcher matches the names of data files against a pattern.
+type matcher struct {
+	pattern string
+	depth   int // number of elements in pattern
+	names   []string
+}
+
+// newMatcher returns a new matcher for pattern.  pattern is assumed to be
+// valid.
+func newMatcher(pattern string) *matcher {
+	m := &matcher{
+		pattern: pattern,
+		depth:   strings.Count(pattern, "/") + 1,
+	}
+
+	return m
+}
+
+// add adds name to the matched names, if it matches the pattern.
+func (m *matcher) add(name string) {
+	if ok, _ := path.Match(m.pattern, name); ok {
+		m.names = append(m.names, name)
+	}
+}
+
+// skip returns true if no file in the directory named by dir can match the
+// pattern.
+func (m *matcher) skip(dir string) bool {
+	return strings.Count(dir, "/")+1 >= m.depth
+}
+
+// result returns the matched names, in lexical order.
+func (m *matcher) result() []string {
+	sort.Strings(m.names)
+
+	return m.names
+}
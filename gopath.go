@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/perillo/data/internal/fsutil"
 	"github.com/perillo/data/internal/gocmd"
 )
 
@@ -23,6 +24,8 @@ type gopathLocator struct {
 //
 // newGopathLocator returns the "null" locator if $GOPATH is not available.
 func newGopathLocator() Locator {
+	info := loadInfo()
+
 	gopath, err := gopath()
 	if err != nil {
 		return &nullLocator{
@@ -64,7 +67,7 @@ func (l *gopathLocator) locate(modpath string) (Loader, error) {
 	// Search the module path in $GOPATH.
 	for _, root := range l.pathList {
 		dirpath := filepath.Join(root, "src", mod.Path)
-		if isDir(dirpath) {
+		if fsutil.IsDir(dirpath) {
 			// It is responsibility of Loader to report an error if the data
 			// directory does not exists.
 			ld := &fsLoader{
@@ -0,0 +1,272 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// handler.go source file implements an http.Handler for module data files.
+
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Handler is an http.Handler that serves module data files from a Loader.
+// The request URL path, without the leading slash, is used as the path of the
+// data file.  Use http.StripPrefix to serve the files under a different path.
+//
+// Range requests and conditional requests are supported.  The Content-Type
+// is derived from the file extension or from the file content, and the
+// Last-Modified header is set from File.Lstat.  The strong ETag is derived
+// from the module checksum, when the file is stored unmodified in the module
+// cache or in a module downloaded from the module proxy, since the module
+// content is immutable.  Otherwise the ETag is derived from the file content,
+// and it is cached until the file size or modification time changes.
+type Handler struct {
+	// Loader is the loader used to load the data files.
+	Loader Loader
+
+	// ListDirectories enables directory listings.  Directories can only be
+	// listed if they are stored on the local filesystem.  By default, a
+	// request for a directory returns the 404 status code.
+	ListDirectories bool
+
+	mu    sync.Mutex
+	etags map[string]etagEntry // ETags derived from the file content, by file
+}
+
+// etagEntry is an ETag derived from the content of a file, with the file size
+// and modification time used to detect changes.
+type etagEntry struct {
+	etag    string
+	size    int64
+	modtime time.Time
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+	f, err := h.Loader.Load(name)
+	if err != nil {
+		httpError(w, err)
+
+		return
+	}
+	fi, err := f.Lstat()
+	if err != nil {
+		httpError(w, err)
+
+		return
+	}
+
+	if fi.IsDir() {
+		h.serveDir(w, r, f)
+
+		return
+	}
+	h.serveFile(w, r, f, fi)
+}
+
+// serveFile replies to the request with the content of the file f.
+func (h *Handler) serveFile(w http.ResponseWriter, r *http.Request, f File,
+	fi os.FileInfo) {
+	mode := fi.Mode()
+	if !mode.IsRegular() && mode&os.ModeSymlink == 0 {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	// http.ServeContent requires an io.ReadSeeker.
+	rs, err := OpenRandom(f)
+	if err != nil && mode&os.ModeSymlink != 0 {
+		// The loader does not support symbolic links, or the link is broken.
+		http.NotFound(w, r)
+
+		return
+	}
+	if err != nil {
+		httpError(w, err)
+
+		return
+	}
+	defer rs.Close()
+
+	etag, err := h.etag(f, fi, rs)
+	if err != nil {
+		httpError(w, err)
+
+		return
+	}
+	w.Header().Set("ETag", etag)
+
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), rs)
+}
+
+// etag returns the strong ETag for the file f, with information fi and
+// content rs.
+func (h *Handler) etag(f File, fi os.FileInfo, rs io.ReadSeeker) (string, error) {
+	hash := sha256.New()
+	if sum, name, ok := moduleSum(f); ok {
+		fmt.Fprintf(hash, "%s %s", sum, name)
+
+		return quoteETag(hash.Sum(nil)), nil
+	}
+
+	// The underlying file is used as the key, since the file served for the
+	// same name may change, as an example with a user override.
+	uf := underlying(f)
+	key := uf.Name() + "\x00" + uf.Path()
+	h.mu.Lock()
+	e, ok := h.etags[key]
+	h.mu.Unlock()
+	if ok && e.size == fi.Size() && e.modtime.Equal(fi.ModTime()) {
+		return e.etag, nil
+	}
+
+	if _, err := io.Copy(hash, rs); err != nil {
+		return "", err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	e = etagEntry{
+		etag:    quoteETag(hash.Sum(nil)),
+		size:    fi.Size(),
+		modtime: fi.ModTime(),
+	}
+
+	h.mu.Lock()
+	if h.etags == nil {
+		h.etags = make(map[string]etagEntry)
+	}
+	h.etags[key] = e
+	h.mu.Unlock()
+
+	return e.etag, nil
+}
+
+// quoteETag returns the strong ETag for the hash sum.
+func quoteETag(sum []byte) string {
+	return `"` + base64.RawURLEncoding.EncodeToString(sum) + `"`
+}
+
+// moduleSum returns the checksum of the module providing the file f, and the
+// name of the file in the module, if the file content is covered by the
+// checksum.  This is the case only when the file is stored unmodified in the
+// module cache, extracted or as a zip file, or in a module downloaded and
+// verified by the "proxy" locator.
+func moduleSum(f File) (sum, name string, ok bool) {
+	if FileLayer(f) == LayerOverride {
+		return "", "", false
+	}
+
+	var (
+		lc Locator
+		ld Loader
+	)
+	uf := underlying(f)
+	switch uf := uf.(type) {
+	case *fsFile:
+		lc, ld = uf.lc, uf.ld
+	case *archiveFile:
+		lc, ld = uf.lc, uf.ld
+	default:
+		return "", "", false
+	}
+	switch lc.Name() {
+	case "fs:modcache", "zip:modcache", "proxy":
+	default:
+		return "", "", false
+	}
+	if sum = ld.Module().Sum; sum == "" {
+		return "", "", false
+	}
+
+	return sum, uf.Name(), true
+}
+
+// serveDir replies to the request with the listing of the directory f.
+func (h *Handler) serveDir(w http.ResponseWriter, r *http.Request, f File) {
+	if !h.ListDirectories || f.Path() == "" {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	// Redirect to the canonical directory URL, so that relative links work.
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		u := *r.URL
+		u.Path += "/"
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+
+		return
+	}
+
+	dir, err := os.Open(f.Path())
+	if err != nil {
+		httpError(w, err)
+
+		return
+	}
+	defer dir.Close()
+
+	list, err := dir.Readdir(-1)
+	if err != nil {
+		httpError(w, err)
+
+		return
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintln(w, "<pre>")
+	for _, fi := range list {
+		name := fi.Name()
+		if fi.IsDir() {
+			name += "/"
+		}
+		u := url.URL{Path: name}
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", u.String(),
+			html.EscapeString(name))
+	}
+	fmt.Fprintln(w, "</pre>")
+}
+
+// httpError replies to the request with the status code corresponding to err.
+// The error message is not sent to the client, since it may contain
+// sensitive information, like a file path.
+func httpError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		http.Error(w, "404 page not found", http.StatusNotFound)
+	case errors.Is(err, os.ErrPermission):
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+	default:
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+}
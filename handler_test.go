@@ -0,0 +1,214 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// namedLocator is a Locator that only reports its name, used to build
+// loaders for the locators of this package.
+type namedLocator string
+
+// Locate implements the Locator interface.
+func (l namedLocator) Locate(modpath string) (Loader, error) {
+	return nil, errors.New("not implemented")
+}
+
+// Name implements the Locator interface.
+func (l namedLocator) Name() string {
+	return string(l)
+}
+
+// newHandlerLoader returns a loader for the locator named by name, serving
+// the handler test files from the data directory in a temporary directory.
+func newHandlerLoader(t *testing.T, name string, mod *Module) Loader {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"data/a.txt":       "0123456789",
+		"data/dir/b.txt":   "b",
+		"data/dir/c/d.txt": "d",
+		"secret.txt":       "secret",
+	})
+	ld := &fsLoader{
+		lc:   namedLocator(name),
+		mod:  mod,
+		root: filepath.Join(root, "data"),
+	}
+
+	return ld
+}
+
+// serve serves the request with the specified method, path and headers.
+func serve(h http.Handler, method, path string,
+	header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/", nil)
+	r.URL.Path = path // not cleaned, to check path traversal
+	for key, value := range header {
+		r.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	mod := &Module{Path: "example.com/m", Version: "v1.0.0"}
+	ld := newHandlerLoader(t, "fs:gopath", mod)
+
+	tests := []struct {
+		desc   string
+		method string
+		path   string
+		header map[string]string
+		list   bool // ListDirectories
+		status int
+		body   string // body substring
+	}{
+		{"file", "GET", "/a.txt", nil, false, 200, "0123456789"},
+		{"head", "HEAD", "/a.txt", nil, false, 200, ""},
+		{"nested file", "GET", "/dir/b.txt", nil, false, 200, "b"},
+		{"missing file", "GET", "/missing.txt", nil, false, 404, ""},
+		{"method", "POST", "/a.txt", nil, false, 405, ""},
+		{"range", "GET", "/a.txt", map[string]string{"Range": "bytes=2-4"},
+			false, 206, "234"},
+		{"suffix range", "GET", "/a.txt",
+			map[string]string{"Range": "bytes=-3"}, false, 206, "789"},
+		{"invalid range", "GET", "/a.txt",
+			map[string]string{"Range": "bytes=20-"}, false, 416, ""},
+		{"traversal", "GET", "/../secret.txt", nil, false, 404, ""},
+		{"nested traversal", "GET", "/dir/../../secret.txt", nil, false, 404,
+			""},
+		{"relative traversal", "GET", "../secret.txt", nil, false, 404, ""},
+		{"directory", "GET", "/dir/", nil, false, 404, ""},
+		{"root", "GET", "/", nil, false, 404, ""},
+		{"list directory", "GET", "/dir/", nil, true, 200,
+			`<a href="b.txt">b.txt</a>` + "\n" + `<a href="c/">c/</a>`},
+		{"list root", "GET", "/", nil, true, 200, `<a href="dir/">dir/</a>`},
+		{"list redirect", "GET", "/dir", nil, true, 301, ""},
+		{"list traversal", "GET", "/../", nil, true, 200, `<a href="a.txt">`},
+	}
+	for _, test := range tests {
+		h := &Handler{
+			Loader:          ld,
+			ListDirectories: test.list,
+		}
+		w := serve(h, test.method, test.path, test.header)
+		if w.Code != test.status {
+			t.Errorf("%s: got status %d, want %d", test.desc, w.Code, test.status)
+
+			continue
+		}
+		if body := w.Body.String(); !strings.Contains(body, test.body) {
+			t.Errorf("%s: got body %q, want %q", test.desc, body, test.body)
+		}
+	}
+
+	// The redirect is to the canonical directory URL.
+	h := &Handler{Loader: ld, ListDirectories: true}
+	w := serve(h, "GET", "/dir", nil)
+	if got, want := w.Header().Get("Location"), "/dir/"; got != want {
+		t.Errorf("redirect: got location %q, want %q", got, want)
+	}
+}
+
+func TestHandlerETag(t *testing.T) {
+	sum := "h1:Z6/aqHArv5C1gRF1oNYxTEsRArrYGNoKvZhjtsDZg2E="
+	summed := &Module{Path: "example.com/m", Version: "v1.0.0", Sum: sum}
+	unsummed := &Module{Path: "example.com/m", Version: "v1.0.0"}
+
+	// The ETag is derived from the module checksum and the file name, or from
+	// the file content.
+	sumETag := quoteETag(sha256Sum(sum + " a.txt"))
+	contentETag := quoteETag(sha256Sum("0123456789"))
+
+	tests := []struct {
+		locator string
+		mod     *Module
+		want    string
+	}{
+		{"fs:modcache", summed, sumETag},
+		{"zip:modcache", summed, sumETag},
+		{"proxy", summed, sumETag},
+		{"fs:modcache", unsummed, contentETag},
+		{"fs:gopath", summed, contentETag},
+		{"fs:user", summed, contentETag},
+	}
+	for _, test := range tests {
+		h := &Handler{
+			Loader: newHandlerLoader(t, test.locator, test.mod),
+		}
+		w := serve(h, "GET", "/a.txt", nil)
+		etag := w.Header().Get("ETag")
+		if etag != test.want {
+			t.Errorf("%s, sum %q: got ETag %s, want %s", test.locator,
+				test.mod.Sum, etag, test.want)
+		}
+
+		// A conditional request with the same ETag is not modified.
+		w = serve(h, "GET", "/a.txt", map[string]string{"If-None-Match": etag})
+		if w.Code != http.StatusNotModified {
+			t.Errorf("%s, sum %q: got status %d, want %d", test.locator,
+				test.mod.Sum, w.Code, http.StatusNotModified)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("%s, sum %q: got body %q for a 304 response",
+				test.locator, test.mod.Sum, w.Body.String())
+		}
+
+		// A conditional request with a different ETag is served.
+		w = serve(h, "GET", "/a.txt", map[string]string{"If-None-Match": `"x"`})
+		if w.Code != http.StatusOK {
+			t.Errorf("%s, sum %q: got status %d, want %d", test.locator,
+				test.mod.Sum, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestHandlerETagModified(t *testing.T) {
+	mod := &Module{Path: "example.com/m", Version: "v1.0.0"}
+	h := &Handler{
+		Loader: newHandlerLoader(t, "fs:gopath", mod),
+	}
+	w := serve(h, "GET", "/a.txt", nil)
+	want := quoteETag(sha256Sum("0123456789"))
+	if got := w.Header().Get("ETag"); got != want {
+		t.Fatalf("got ETag %s, want %s", got, want)
+	}
+
+	// The cached ETag is invalidated when the file changes.
+	f, err := h.Loader.Load("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(f.Path(), []byte("modified"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(f.Path(), future, future); err != nil {
+		t.Fatal(err)
+	}
+	w = serve(h, "GET", "/a.txt", nil)
+	want = quoteETag(sha256Sum("modified"))
+	if got := w.Header().Get("ETag"); got != want {
+		t.Errorf("modified: got ETag %s, want %s", got, want)
+	}
+}
+
+// sha256Sum returns the SHA-256 checksum of s.
+func sha256Sum(s string) []byte {
+	sum := sha256.Sum256([]byte(s))
+
+	return sum[:]
+}
@@ -0,0 +1,432 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// http.go source file implements the "http" locator, and the Loader and File
+// interface for files fetched from a data server.
+
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perillo/data/internal/fsutil"
+)
+
+// httpClient is the client used to fetch data from the network.  It has
+// timeouts, so that a stalled server does not block the data loading forever.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          100,
+	},
+	Timeout: 10 * time.Minute,
+}
+
+// httpLocator implements the "http" locator that locates a module on a data
+// server.
+type httpLocator struct {
+	base   string // base URL, without the trailing slash
+	cache  string // absolute path to the cache directory
+	client *http.Client
+
+	mu    sync.Mutex
+	avail map[string]bool // modules known to be available, by URL
+}
+
+// NewHTTPLocator returns a new "http" locator, for modules stored on the data
+// server at the base URL.
+//
+// The URL of a data file is <base>/<module>/@v/<version>/data/<path>, where
+// module and version are escaped as in the module cache, with each upper case
+// letter replaced by an exclamation mark followed by the letter's lower case.
+// A module is available if the server responds with a 2xx status code to a
+// HEAD request for the module data directory,
+// <base>/<module>/@v/<version>/data/, so the server must serve the data
+// directory, as an example with a directory listing or an empty response.
+// Any other status code, like 403 Forbidden returned by object stores for
+// missing keys, makes the module unavailable.
+//
+// The files are fetched lazily, when calling File.Lstat or File.Open, and
+// stored in the user cache directory.  Cached files are revalidated with the
+// ETag sent by the server, and they are used if the server is not reachable.
+// The requests time out if the server stalls.
+//
+// NewHTTPLocator returns the "null" locator if build info or the user cache
+// directory is not available, base is not a valid http or https URL, or the
+// main module is not available on the server.
+func NewHTTPLocator(base string) Locator {
+	info := loadInfo()
+	if info == nil {
+		return &nullLocator{
+			err: errors.New("build info is not available"),
+		}
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &nullLocator{
+			err: fmt.Errorf("unsupported URL scheme %q", u.Scheme),
+		}
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	return newHTTPLocator(base, filepath.Join(dir, "go-data", "http"), httpClient)
+}
+
+// newHTTPLocator returns a new "http" locator, for modules stored on the data
+// server at the base URL, using the cache directory cache and the HTTP client.
+func newHTTPLocator(base, cache string, client *http.Client) Locator {
+	info := loadInfo()
+
+	l := &httpLocator{
+		base:   strings.TrimSuffix(base, "/"),
+		cache:  cache,
+		client: client,
+		avail:  make(map[string]bool),
+	}
+
+	// Check if the main module is on the data server.
+	if _, err := l.locate(info.Main.Path); err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	return l
+}
+
+// Locate implements the Locator interface.
+func (l *httpLocator) Locate(modpath string) (Loader, error) {
+	ld, err := l.locate(modpath)
+	if err != nil {
+		return nil, mkerr(l, err)
+	}
+
+	return ld, nil
+}
+
+func (l *httpLocator) locate(modpath string) (Loader, error) {
+	// Find module in build info.
+	mod, err := find(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	epath, err := escape(mod.Path)
+	if err != nil {
+		return nil, err
+	}
+	eversion, err := escape(mod.Version)
+	if err != nil {
+		return nil, err
+	}
+	root := l.base + "/" + epath + "/@v/" + url.PathEscape(eversion) + "/data"
+	cache := filepath.Join(l.cache, filepath.FromSlash(epath), "@v", eversion)
+	if err := l.available(root, cache); err != nil {
+		return nil, fmt.Errorf("module %s is not available: %v", modpath, err)
+	}
+
+	// It is responsibility of File to report an error if path does not exists.
+	ld := &httpLoader{
+		lc:     l,
+		mod:    mod,
+		root:   root,
+		cache:  cache,
+		client: l.client,
+	}
+
+	return ld, nil
+}
+
+// available checks if the module data directory at the URL root is available
+// on the data server.  If the server is not reachable or responds with a
+// server error, the module is available if it has files in the cache
+// directory.
+//
+// Only the modules that are available are remembered, so that a module is
+// checked again after an error.
+func (l *httpLocator) available(root, cache string) error {
+	l.mu.Lock()
+	ok := l.avail[root]
+	l.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	resp, err := l.client.Head(root + "/")
+	if err != nil {
+		if fsutil.IsDir(cache) {
+			return nil
+		}
+
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500 && fsutil.IsDir(cache):
+		// The server is not working, use the cache.
+		return nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("data server responded with %s", resp.Status)
+	}
+
+	l.mu.Lock()
+	l.avail[root] = true
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Name implements the Locator interface.
+func (l *httpLocator) Name() string {
+	return "http"
+}
+
+// httpLoader implements a Loader that loads module data from a data server.
+type httpLoader struct {
+	lc     Locator
+	mod    *Module
+	root   string // URL of the module data directory
+	cache  string // absolute path to the module cache directory
+	client *http.Client
+}
+
+// Load implements the Loader interface.
+func (l *httpLoader) Load(path string) (File, error) {
+	f, err := l.load(path)
+	if err != nil {
+		return nil, mkerr(l.lc, l, err)
+	}
+
+	return f, nil
+}
+
+func (l *httpLoader) load(name string) (File, error) {
+	if filepath.IsAbs(name) || path.IsAbs(name) {
+		return nil, fmt.Errorf("path %s is not a relative path", name)
+	}
+
+	// Escape each path element.
+	elems := strings.Split(path.Clean(filepath.ToSlash(name)), "/")
+	for i, elem := range elems {
+		if elem == ".." {
+			return nil, fmt.Errorf("path %s is outside the data directory", name)
+		}
+		elems[i] = url.PathEscape(elem)
+	}
+	u := l.root + "/" + strings.Join(elems, "/")
+
+	// The cache file is named by the URL hash, so that it is not necessary to
+	// escape the URL.
+	h := sha256.Sum256([]byte(u))
+
+	// It is responsibility of File to report an error if path does not exists.
+	file := &httpFile{
+		lc:    l.lc,
+		ld:    l,
+		url:   u,
+		cache: filepath.Join(l.cache, hex.EncodeToString(h[:])),
+		path:  name,
+	}
+
+	return file, nil
+}
+
+// Module implements the Loader interface.
+func (l *httpLoader) Module() *Module {
+	return l.mod
+}
+
+// httpFile represents a file on a data server.
+type httpFile struct {
+	lc    Locator
+	ld    *httpLoader
+	url   string // URL of the data file
+	cache string // path to the cached file
+	path  string // path to the data file, relative to the module data directory
+
+	once sync.Once
+	err  error // error from fetch
+}
+
+// Name implements the File interface.
+func (f *httpFile) Name() string {
+	return f.path
+}
+
+// Path implements the File interface.  The file is stored on a data server,
+// so Path always returns an empty string.
+func (f *httpFile) Path() string {
+	return ""
+}
+
+// Lstat implements the File interface.
+func (f *httpFile) Lstat() (os.FileInfo, error) {
+	if err := f.fetchOnce(); err != nil {
+		return nil, mkerr(f.lc, f.ld, f, "lstat", err)
+	}
+
+	fi, err := os.Stat(f.cache)
+	if err != nil {
+		return nil, mkerr(f.lc, f.ld, f, "lstat", err)
+	}
+
+	return &namedInfo{fi, path.Base(filepath.ToSlash(f.path))}, nil
+}
+
+// Open implements the File interface.
+func (f *httpFile) Open() (io.ReadCloser, error) {
+	if err := f.fetchOnce(); err != nil {
+		return nil, mkerr(f.lc, f.ld, f, "open", err)
+	}
+
+	rc, err := os.Open(f.cache)
+	if err != nil {
+		return nil, mkerr(f.lc, f.ld, f, "open", err)
+	}
+
+	return rc, nil
+}
+
+// OpenRandom implements the RandomFile interface.
+func (f *httpFile) OpenRandom() (RandomReader, error) {
+	if err := f.fetchOnce(); err != nil {
+		return nil, mkerr(f.lc, f.ld, f, "open", err)
+	}
+
+	r, err := openFile(f.cache)
+	if err != nil {
+		return nil, mkerr(f.lc, f.ld, f, "open", err)
+	}
+
+	return r, nil
+}
+
+// fetchOnce calls fetch only once for each file.
+func (f *httpFile) fetchOnce() error {
+	f.once.Do(func() {
+		f.err = f.fetch()
+	})
+
+	return f.err
+}
+
+// fetch updates the cached file, if it has been modified on the server.
+func (f *httpFile) fetch() error {
+	req, err := http.NewRequest("GET", f.url, nil)
+	if err != nil {
+		return err
+	}
+	cached := isFile(f.cache)
+	if etag, err := os.ReadFile(f.cache + ".etag"); err == nil && cached {
+		req.Header.Set("If-None-Match", string(etag))
+	}
+
+	resp, err := f.ld.client.Do(req)
+	if err != nil {
+		if cached {
+			// Use the cached file, since the server is not reachable.
+			return nil
+		}
+
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil
+	case http.StatusNotFound, http.StatusGone:
+		os.Remove(f.cache)
+		os.Remove(f.cache + ".etag")
+
+		return os.ErrNotExist
+	default:
+		return fmt.Errorf("GET %s: %s", f.url, resp.Status)
+	}
+
+	// Update the cache atomically.
+	if err := os.MkdirAll(filepath.Dir(f.cache), 0777); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.cache), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		os.Chtimes(tmp.Name(), time.Now(), t)
+	}
+
+	// The ETag is removed before updating the file, and stored after, so that
+	// a stale ETag is never used with an updated file.
+	os.Remove(f.cache + ".etag")
+	if err := os.Rename(tmp.Name(), f.cache); err != nil {
+		return err
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return os.WriteFile(f.cache+".etag", []byte(etag), 0666)
+	}
+
+	return nil
+}
+
+// namedInfo is an os.FileInfo with a different name.
+type namedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi *namedInfo) Name() string { return fi.name }
+
+// isFile returns true if path exists and it is a regular file.
+func isFile(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode().IsRegular()
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// dataServer is a data server for testing, serving the files of the main
+// module.
+type dataServer struct {
+	mu       sync.Mutex
+	files    map[string]string // file contents, by name
+	modified int               // number of 200 responses for files
+	cached   int               // number of 304 responses for files
+}
+
+func (s *dataServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The path is /<module>/@v/<version>/data/<name>.
+	idx := strings.Index(r.URL.Path, "/@v/")
+	if idx < 0 {
+		http.NotFound(w, r)
+
+		return
+	}
+	elems := strings.SplitN(r.URL.Path[idx+len("/@v/"):], "/", 3)
+	if len(elems) != 3 || elems[1] != "data" {
+		http.NotFound(w, r)
+
+		return
+	}
+	name := elems[2]
+	if name == "" {
+		// The module data directory.
+		w.WriteHeader(http.StatusOK)
+
+		return
+	}
+
+	content, ok := s.files[name]
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(content)))
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		s.cached++
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+	s.modified++
+	io.WriteString(w, content)
+}
+
+// set sets the content of the file named by name.
+func (s *dataServer) set(name, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[name] = content
+}
+
+// counts returns the number of 200 and 304 responses for files.
+func (s *dataServer) counts() (modified, cached int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.modified, s.cached
+}
+
+// readAll returns the content of the file named by name, loaded by ld.
+func readAll(ld Loader, name string) (string, error) {
+	f, err := ld.Load(name)
+	if err != nil {
+		return "", err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	buf, err := io.ReadAll(rc)
+
+	return string(buf), err
+}
+
+func TestHTTPLocator(t *testing.T) {
+	ds := &dataServer{
+		files: map[string]string{
+			"a.txt": "version 1",
+		},
+	}
+	srv := httptest.NewServer(ds)
+	defer srv.Close()
+
+	lc := newHTTPLocator(srv.URL, t.TempDir(), srv.Client())
+	if name := lc.Name(); name != "http" {
+		t.Fatalf("newHTTPLocator: got locator %q, want %q", name, "http")
+	}
+	ld, err := lc.Locate(mainPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		desc     string
+		update   string // new content on the server, if not empty
+		want     string
+		modified int
+		cached   int
+	}{
+		{"first fetch", "", "version 1", 1, 0},
+		{"revalidate", "", "version 1", 1, 1},
+		{"modified", "version 2", "version 2", 2, 1},
+		{"revalidate modified", "", "version 2", 2, 2},
+	}
+	for _, test := range tests {
+		if test.update != "" {
+			ds.set("a.txt", test.update)
+		}
+		got, err := readAll(ld, "a.txt")
+		if err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.desc, got, test.want)
+		}
+		modified, cached := ds.counts()
+		if modified != test.modified || cached != test.cached {
+			t.Errorf("%s: got %d modified and %d cached responses, want %d and %d",
+				test.desc, modified, cached, test.modified, test.cached)
+		}
+	}
+
+	// A missing file is reported as not existing.
+	if _, err := readAll(ld, "missing.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: got error %v, want %v", err, os.ErrNotExist)
+	}
+
+	// The cached file is used when the server is not reachable.
+	srv.Close()
+	got, err := readAll(ld, "a.txt")
+	if err != nil {
+		t.Fatalf("offline: %v", err)
+	}
+	if want := "version 2"; got != want {
+		t.Errorf("offline: got %q, want %q", got, want)
+	}
+}
+
+func TestHTTPLocatorAvailable(t *testing.T) {
+	setInfo(t, testInfo)
+
+	tests := []struct {
+		status int
+		cached bool // the module has files in the cache directory
+		want   string
+	}{
+		{http.StatusOK, false, "http"},
+		{http.StatusNoContent, false, "http"},
+		{http.StatusForbidden, false, "null"},
+		{http.StatusNotFound, false, "null"},
+		{http.StatusGone, false, "null"},
+		{http.StatusForbidden, true, "null"},
+		{http.StatusServiceUnavailable, false, "null"},
+		{http.StatusServiceUnavailable, true, "http"},
+	}
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+			}))
+
+		cache := t.TempDir()
+		if test.cached {
+			writeFiles(t, cache, map[string]string{
+				"example.com/app/@v/(devel)/a.txt": "cached",
+			})
+		}
+		lc := newHTTPLocator(srv.URL, cache, srv.Client())
+		if name := lc.Name(); name != test.want {
+			t.Errorf("status %d, cached %v: got locator %q, want %q",
+				test.status, test.cached, name, test.want)
+		}
+		srv.Close()
+	}
+}
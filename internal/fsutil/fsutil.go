@@ -0,0 +1,19 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fsutil implements file system helpers shared by the data package
+// and the go-data command.
+package fsutil
+
+import "os"
+
+// IsDir returns true if path exists and it is a directory.
+func IsDir(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return fi.IsDir()
+}
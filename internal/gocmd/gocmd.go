@@ -4,14 +4,35 @@
 
 package gocmd
 
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
 // Getenv returns the named Go environment variable.
 //
 // If key does not exist, Getenv returns an empty string.
 func Getenv(key string) (string, error) {
-	stdout, err := Invoke("env", "GOPATH")
+	stdout, err := Invoke("env", key)
 	if err != nil {
 		return "", err
 	}
 
 	return string(stdout), nil
 }
+
+// ModRoot returns the root directory of the main module, as reported by the
+// go command in the current directory.
+func ModRoot() (string, error) {
+	gomod, err := Getenv("GOMOD")
+	if err != nil {
+		return "", err
+	}
+	if gomod == "" || gomod == os.DevNull {
+		return "", errors.New("go.mod file not found in current directory " +
+			"or any parent directory")
+	}
+
+	return filepath.Dir(gomod), nil
+}
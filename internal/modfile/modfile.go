@@ -0,0 +1,131 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modfile implements a minimal parser for the go.mod, go.work and
+// vendor/modules.txt files.
+package modfile
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ParseWork parses the go.work file at path, and returns a map from the path
+// of each module in the use list to its root directory.
+func ParseWork(path string) (map[string]string, error) {
+	dirs, err := parseDirective(path, "use")
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Dir(path)
+	mods := make(map[string]string, len(dirs))
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+
+		modpath, err := ModulePath(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		mods[modpath] = dir
+	}
+
+	return mods, nil
+}
+
+// ModulePath returns the module path declared in the go.mod file at path.
+func ModulePath(path string) (string, error) {
+	args, err := parseDirective(path, "module")
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("%s: missing module directive", path)
+	}
+
+	return args[0], nil
+}
+
+// GoVersion returns the Go version declared in the go.mod file at path, or an
+// empty string if the go directive is missing.
+func GoVersion(path string) (string, error) {
+	args, err := parseDirective(path, "go")
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+
+	return args[0], nil
+}
+
+// parseDirective returns the first argument of all the verb directives in the
+// go.mod or go.work file at path, including the ones in a block.
+func parseDirective(path, verb string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var args []string
+	inblock := false
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case inblock && fields[0] == ")":
+			inblock = false
+
+			continue
+		case inblock:
+		case fields[0] != verb:
+			continue
+		case len(fields) > 1 && fields[1] == "(":
+			inblock = true
+
+			continue
+		default:
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%s:%d: missing %s argument", path, n, verb)
+		}
+
+		arg, err := unquote(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		args = append(args, arg)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return args, nil
+}
+
+// unquote unquotes s if it is a Go interpreted or raw string literal.
+func unquote(s string) (string, error) {
+	if s == "" || (s[0] != '"' && s[0] != '`') {
+		return s, nil
+	}
+
+	return strconv.Unquote(s)
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfile
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Module represents a module listed in vendor/modules.txt.
+type Module struct {
+	Path           string // module path
+	Version        string // module version, empty if not specified
+	Replace        string // replacement module path or directory, if any
+	ReplaceVersion string // replacement module version, if any
+}
+
+// ParseVendor parses the vendor/modules.txt file at path, and returns the
+// vendored modules.
+func ParseVendor(path string) ([]Module, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mods []Module
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+
+		// Module lines have the form "# path [version] [=> path [version]]".
+		// Lines starting with "##" are annotations, and other lines are
+		// package paths.
+		if !strings.HasPrefix(line, "# ") {
+			continue
+		}
+		fields := strings.Fields(line[2:])
+
+		var mod Module
+		if idx := index(fields, "=>"); idx >= 0 {
+			repl := fields[idx+1:]
+			fields = fields[:idx]
+			if len(repl) == 0 || len(repl) > 2 {
+				return nil, fmt.Errorf("%s:%d: malformed replacement", path, n)
+			}
+			mod.Replace = repl[0]
+			if len(repl) == 2 {
+				mod.ReplaceVersion = repl[1]
+			}
+		}
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("%s:%d: malformed module line", path, n)
+		}
+		mod.Path = fields[0]
+		if len(fields) == 2 {
+			mod.Version = fields[1]
+		}
+		mods = append(mods, mod)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return mods, nil
+}
+
+// index returns the index of the first instance of s in list, or -1 if s is
+// not present in list.
+func index(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+
+	return -1
+}
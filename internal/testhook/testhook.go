@@ -0,0 +1,22 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package testhook allows the datatest package to change the internal state
+// of the data package, without exporting it.
+package testhook
+
+// Module mirrors the data.Module type.
+type Module struct {
+	Path    string
+	Version string
+	Sum     string
+	Replace *Module
+}
+
+// SetBuildInfo replaces the build info used by the data package, with the main
+// package path, the main module and the module dependencies.  It returns a
+// function that restores the previous build info.
+//
+// SetBuildInfo is set by the data package.
+var SetBuildInfo func(path string, main Module, deps []Module) (restore func())
@@ -0,0 +1,174 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// locale.go source file implements a Loader that loads localized data files.
+
+package data
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// localeLoader implements a Loader that loads the data files localized for a
+// list of language tags.
+type localeLoader struct {
+	ld   Loader
+	dirs []string // localized directories, in lookup order
+}
+
+// NewLocaleLoader returns a Loader that wraps ld, so that Load("name") loads
+// the file localized for the first language tag, in BCP 47 format, for which
+// it exists.  For each tag, like "zh-Hant-TW", the directories zh-Hant-TW,
+// zh-Hant and zh are searched in order, as done by the BCP 47 lookup.  When no
+// localized file exists, the file in the data directory is loaded.
+//
+// The language tags are normalized, so that "pt_BR.UTF-8" is the same as
+// "pt-BR".  The directories must use the canonical case, like "pt-BR".
+//
+// Unlike other loaders, Load accesses the data files to find the localized
+// file.  The Name method of the returned file reports the localized name, like
+// "pt-BR/messages.json".
+func NewLocaleLoader(ld Loader, tags ...string) Loader {
+	l := &localeLoader{
+		ld:   ld,
+		dirs: localeDirs(tags),
+	}
+
+	return l
+}
+
+// Load implements the Loader interface.
+func (l *localeLoader) Load(path string) (File, error) {
+	for _, dir := range l.dirs {
+		f, err := l.ld.Load(dir + "/" + path)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = f.Lstat()
+		switch {
+		case err == nil:
+			return f, nil
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	return l.ld.Load(path)
+}
+
+// Module implements the Loader interface.
+func (l *localeLoader) Module() *Module {
+	return l.ld.Module()
+}
+
+// UserLanguages returns the user preferred languages, in BCP 47 format, as
+// specified by the LANGUAGE, LC_ALL, LC_MESSAGES and LANG environment
+// variables, following the gettext conventions.  The "C" and "POSIX" locales
+// are ignored.
+func UserLanguages() []string {
+	// LANGUAGE is a colon separated list, and it is used only if the locale
+	// is not "C".
+	var tags []string
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(key); value != "" {
+			tags = append(tags, value)
+
+			break
+		}
+	}
+	if len(tags) == 0 || isCLocale(tags[0]) {
+		return nil
+	}
+	if value := os.Getenv("LANGUAGE"); value != "" {
+		tags = append(strings.Split(value, ":"), tags...)
+	}
+
+	langs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = normalizeTag(tag); tag != "" && !isCLocale(tag) {
+			langs = append(langs, tag)
+		}
+	}
+
+	return langs
+}
+
+// isCLocale returns true if locale is the "C" or "POSIX" locale.
+func isCLocale(locale string) bool {
+	switch normalizeTag(locale) {
+	case "c", "posix":
+		return true
+	}
+
+	return false
+}
+
+// localeDirs returns the localized directories for tags, in lookup order and
+// without duplicates.
+func localeDirs(tags []string) []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		for tag != "" {
+			if !seen[tag] {
+				seen[tag] = true
+				dirs = append(dirs, tag)
+			}
+			tag = parentTag(tag)
+		}
+	}
+
+	return dirs
+}
+
+// parentTag returns the language tag obtained by removing the last subtag from
+// tag, or an empty string.  A single character subtag, like the "x" in
+// "en-x-private", is removed together with the last subtag.
+func parentTag(tag string) string {
+	idx := strings.LastIndexByte(tag, '-')
+	if idx < 0 {
+		return ""
+	}
+	tag = tag[:idx]
+	if idx := strings.LastIndexByte(tag, '-'); idx >= 0 && len(tag)-idx == 2 {
+		tag = tag[:idx]
+	}
+
+	return tag
+}
+
+// normalizeTag converts a language tag or a POSIX locale name, like
+// "pt_BR.UTF-8", to a BCP 47 language tag with the canonical case, like
+// "pt-BR".
+func normalizeTag(tag string) string {
+	// Remove the POSIX codeset and modifier.
+	if idx := strings.IndexAny(tag, ".@"); idx >= 0 {
+		tag = tag[:idx]
+	}
+
+	subtags := strings.FieldsFunc(tag, func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	for i, s := range subtags {
+		switch {
+		case i == 0:
+			s = strings.ToLower(s)
+		case len(s) == 2:
+			// Region.
+			s = strings.ToUpper(s)
+		case len(s) == 4:
+			// Script.
+			s = strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+		default:
+			s = strings.ToLower(s)
+		}
+		subtags[i] = s
+	}
+
+	return strings.Join(subtags, "-")
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"", ""},
+		{"en", "en"},
+		{"EN", "en"},
+		{"pt_BR", "pt-BR"},
+		{"pt_BR.UTF-8", "pt-BR"},
+		{"sr_RS@latin", "sr-RS"},
+		{"zh_hant_tw", "zh-Hant-TW"},
+		{"ZH-HANT-TW", "zh-Hant-TW"},
+		{"de-CH-1996", "de-CH-1996"},
+		{"C", "c"},
+		{"POSIX", "posix"},
+	}
+	for _, test := range tests {
+		if got := normalizeTag(test.tag); got != test.want {
+			t.Errorf("normalizeTag(%q): got %q, want %q", test.tag, got, test.want)
+		}
+	}
+}
+
+func TestParentTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"", ""},
+		{"en", ""},
+		{"en-US", "en"},
+		{"zh-Hant-TW", "zh-Hant"},
+		{"zh-Hant", "zh"},
+		{"en-x-private", "en"},
+		{"de-CH-x-phonebk", "de-CH"},
+	}
+	for _, test := range tests {
+		if got := parentTag(test.tag); got != test.want {
+			t.Errorf("parentTag(%q): got %q, want %q", test.tag, got, test.want)
+		}
+	}
+}
+
+func TestLocaleDirs(t *testing.T) {
+	got := localeDirs([]string{"zh_Hant_TW.UTF-8", "zh-TW", "en"})
+	want := []string{"zh-Hant-TW", "zh-Hant", "zh", "zh-TW", "en"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("localeDirs: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"syscall"
+)
+
+// mmap returns a RandomReader for the file at path, memory mapped in read only
+// mode.
+func mmap(path string) (RandomReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, errors.New("not a regular file")
+	}
+
+	size := fi.Size()
+	if size == 0 {
+		// An empty file can not be mapped.
+		return nopCloser{bytes.NewReader(nil)}, nil
+	}
+	if int64(int(size)) != size {
+		return nil, errors.New("file is too large to be mapped")
+	}
+
+	// The mapping remains valid after the file is closed.
+	buf, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ,
+		syscall.MAP_SHARED)
+	if err != nil {
+		return nil, os.NewSyscallError("mmap", err)
+	}
+
+	return &mmapReader{bytes.NewReader(buf), buf}, nil
+}
+
+// mmapReader is a RandomReader for a memory mapped file.
+type mmapReader struct {
+	*bytes.Reader
+	buf []byte
+}
+
+// Close implements the io.Closer interface.
+func (r *mmapReader) Close() error {
+	if r.buf == nil {
+		return errors.New("file already closed")
+	}
+	buf := r.buf
+	r.buf = nil
+	r.Reader = bytes.NewReader(nil)
+
+	return os.NewSyscallError("munmap", syscall.Munmap(buf))
+}
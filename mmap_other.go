@@ -0,0 +1,14 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package data
+
+// mmap returns errNoMmap, since memory mapping is not supported on this
+// system.
+func mmap(path string) (RandomReader, error) {
+	return nil, errNoMmap
+}
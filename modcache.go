@@ -10,6 +10,8 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+
+	"github.com/perillo/data/internal/fsutil"
 )
 
 // modcacheLocator implements the "fs:modcache" locator that locates a module
@@ -24,6 +26,8 @@ type modcacheLocator struct {
 // newModcacheLocator returns the "null" locator if $GOPATH is not available or
 // the main module is not stored in the Go module cache.
 func newModcacheLocator() Locator {
+	info := loadInfo()
+
 	gocache, err := gocache()
 	if err != nil {
 		return &nullLocator{
@@ -64,7 +68,7 @@ func (l *modcacheLocator) locate(modpath string) (Loader, error) {
 
 	// Check that the module is in the cache.
 	dirpath := filepath.Join(l.path, mod.String())
-	if !isDir(dirpath) {
+	if !fsutil.IsDir(dirpath) {
 		return nil, fmt.Errorf("module %s is not in the cache", mod)
 	}
 
@@ -94,7 +98,7 @@ func gocache() (string, error) {
 	// The module cache is in the first entry of $GOPATH.
 	root := filepath.SplitList(gopath)[0]
 	path := filepath.Join(root, "pkg", "mod")
-	if !isDir(path) {
+	if !fsutil.IsDir(path) {
 		// This may improve the user experience, since it easy to clean the
 		// module cache with go clean -modcache.
 		return "", errors.New("module cache is not available")
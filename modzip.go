@@ -0,0 +1,129 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// modzip.go source file implements the "zip:modcache" locator.
+
+package data
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// modzipLocator implements the "zip:modcache" locator that locates a module
+// zip file in the Go module cache download directory.
+type modzipLocator struct {
+	path  string // path to the module cache download directory
+	cache *zipCache
+}
+
+// newModzipLocator returns a new "zip:modcache" locator, for module zip files
+// in the Go module cache download directory.
+//
+// newModzipLocator returns the "null" locator if $GOPATH is not available or
+// the main module zip file is not stored in the Go module cache.
+func newModzipLocator() Locator {
+	info := loadInfo()
+
+	gocache, err := gocache()
+	if err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	l := &modzipLocator{
+		path:  filepath.Join(gocache, "cache", "download"),
+		cache: new(zipCache),
+	}
+
+	// Check if the main module is in the module cache.
+	if _, err := l.locate(info.Main.Path); err != nil {
+		return &nullLocator{
+			err: fmt.Errorf("main module %s is not in the module cache", &info.Main),
+		}
+	}
+
+	return l
+}
+
+// Locate implements the Locator interface.
+func (l *modzipLocator) Locate(modpath string) (Loader, error) {
+	ld, err := l.locate(modpath)
+	if err != nil {
+		return nil, mkerr(l, err)
+	}
+
+	return ld, nil
+}
+
+func (l *modzipLocator) locate(modpath string) (Loader, error) {
+	// Find module in build info.
+	mod, err := find(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The module zip file is stored in <path>/@v/<version>.zip, with path and
+	// version escaped.
+	epath, err := escape(mod.Path)
+	if err != nil {
+		return nil, err
+	}
+	eversion, err := escape(mod.Version)
+	if err != nil {
+		return nil, err
+	}
+	zippath := filepath.Join(l.path, filepath.FromSlash(epath), "@v",
+		eversion+".zip")
+
+	ar, err := l.cache.open(zippath)
+	if err != nil {
+		return nil, fmt.Errorf("module %s is not in the cache: %v", mod, err)
+	}
+
+	// All the files in the module zip file are stored in <path>@<version>.
+	// It is responsibility of Loader to report an error if the data directory
+	// does not exists.
+	ld := &archiveLoader{
+		lc:   l,
+		mod:  mod,
+		ar:   ar,
+		root: mod.String() + "/data",
+	}
+
+	return ld, nil
+}
+
+// Name implements the Locator interface.
+func (l modzipLocator) Name() string {
+	return "zip:modcache"
+}
+
+// escape returns the safe encoding of the module path or version s, as used in
+// the module cache, where each upper case letter is replaced by an exclamation
+// mark followed by the letter's lower case.
+func escape(s string) (string, error) {
+	if s == "" {
+		return "", errors.New("empty module path or version")
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '!' || r >= utf8.RuneSelf:
+			return "", fmt.Errorf("invalid module path or version %q", s)
+		case 'A' <= r && r <= 'Z':
+			b.WriteByte('!')
+			b.WriteRune(r + 'a' - 'A')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String(), nil
+}
@@ -2,7 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// The UserDataDir function has been adapted from os.UserConfigDir.
+// The UserDataDir, UserStateDir and SystemDataDirs functions have been adapted
+// from os.UserConfigDir.
 // Copyright 2009 The Go Authors. All rights reserved.
 
 package data
@@ -10,6 +11,7 @@ package data
 import (
 	"errors"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 )
@@ -19,6 +21,8 @@ import (
 //
 // If build info is not available, it returns an empty string.
 func AppName() string {
+	info := loadInfo()
+
 	// TODO(mperillo): Implement AppName to return a Java package like path.
 	if info == nil {
 		return ""
@@ -83,3 +87,79 @@ func UserDataDir() (string, error) {
 
 	return dir, nil
 }
+
+// UserStateDir returns the default root directory to use for user-specific
+// state data, like logs and history, that should persist between restarts but
+// is not important enough to be stored in UserDataDir.  Users should create
+// their own application-specific subdirectory within this one and use that.
+//
+// On Unix systems, it returns $XDG_STATE_HOME as specified by
+// https://specifications.freedesktop.org/basedir-spec/basedir-spec-latest.html
+// if non-empty, else $HOME/.local/state.
+// On other systems, it returns the same directory as UserDataDir.
+//
+// If the location cannot be determined (for example, $HOME is not defined),
+// then it will return an error.
+func UserStateDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows", "darwin", "plan9":
+		return UserDataDir()
+	}
+
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		dir = os.Getenv("HOME")
+		if dir == "" {
+			return "", errors.New("neither $XDG_STATE_HOME nor $HOME are defined")
+		}
+		dir += "/.local/state"
+	}
+
+	return dir, nil
+}
+
+// SystemDataDirs returns the default root directories to use for system-wide
+// data, in order of preference.  Applications should look for their own
+// application-specific subdirectory within these ones.
+//
+// On Unix systems, it returns $XDG_DATA_DIRS as specified by
+// https://specifications.freedesktop.org/basedir-spec/basedir-spec-latest.html
+// if non-empty, else /usr/local/share and /usr/share.
+// On Darwin, it returns /Library/Application Support.
+// On Windows, it returns %ProgramData%.
+// On Plan 9, it returns /lib.
+//
+// If the location cannot be determined (for example, %ProgramData% is not
+// defined), then it will return an error.
+func SystemDataDirs() ([]string, error) {
+	var dirs []string
+
+	switch runtime.GOOS {
+	case "windows":
+		dir := os.Getenv("ProgramData")
+		if dir == "" {
+			return nil, errors.New("%ProgramData% is not defined")
+		}
+		dirs = []string{dir}
+
+	case "darwin":
+		dirs = []string{"/Library/Application Support"}
+
+	case "plan9":
+		dirs = []string{"/lib"}
+
+	default: // Unix
+		for _, dir := range filepath.SplitList(os.Getenv("XDG_DATA_DIRS")) {
+			// The specification requires paths to be absolute, and relative
+			// paths should be ignored.
+			if filepath.IsAbs(dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+		if len(dirs) == 0 {
+			dirs = []string{"/usr/local/share", "/usr/share"}
+		}
+	}
+
+	return dirs, nil
+}
@@ -0,0 +1,328 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// override.go source file implements the user overrides of the data files.
+
+package data
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/perillo/data/internal/fsutil"
+)
+
+// The layers reported by FileLayer.
+const (
+	LayerModule   = "module"   // the file is from the module data
+	LayerOverride = "override" // the file is a user override
+)
+
+// OverridesDir returns the directory with the user overrides of the data files
+// of the module named by modpath.  It is the overrides subdirectory of the
+// ConfigDir writable directory, and it is not created.
+func OverridesDir(modpath string) (string, error) {
+	dir, err := writableDir(ConfigDir, modpath)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "overrides"), nil
+}
+
+// overrideLocator implements the "fs:override" locator that locates the user
+// overrides of a module.
+type overrideLocator struct{}
+
+// Locate implements the Locator interface.
+func (l *overrideLocator) Locate(modpath string) (Loader, error) {
+	ld, err := l.locate(modpath)
+	if err != nil {
+		return nil, mkerr(l, err)
+	}
+
+	return ld, nil
+}
+
+func (l *overrideLocator) locate(modpath string) (Loader, error) {
+	mod, err := find(modpath)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := OverridesDir(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	// It is responsibility of Loader to report an error if the overrides
+	// directory does not exists.
+	ld := &fsLoader{
+		lc:   l,
+		mod:  mod,
+		root: dir,
+	}
+
+	return ld, nil
+}
+
+// Name implements the Locator interface.
+func (l overrideLocator) Name() string {
+	return "fs:override"
+}
+
+// overrideLoader implements a Loader that prefers the user overrides of the
+// data files.
+type overrideLoader struct {
+	ld   Loader
+	over *fsLoader // the overrides, nil if not available
+}
+
+// NewOverrideLoader returns a Loader that wraps ld, so that when the user has
+// an override of a data file in OverridesDir, the override is loaded instead.
+// The layer that served a file is reported by FileLayer.
+//
+// A user can customize a data file by editing the copy created by Eject.
+func NewOverrideLoader(ld Loader) Loader {
+	info := loadInfo()
+
+	if _, ok := ld.(*overrideLoader); ok {
+		return ld
+	}
+
+	l := &overrideLoader{
+		ld: ld,
+	}
+	if info != nil {
+		if over, err := new(overrideLocator).locate(ld.Module().Path); err == nil {
+			l.over = over.(*fsLoader)
+		}
+	}
+
+	return l
+}
+
+// Load implements the Loader interface.
+func (l *overrideLoader) Load(path string) (File, error) {
+	f, err := l.ld.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The override is resolved lazily, so that Load does not access the data
+	// files.
+	file := &overrideFile{
+		l: l,
+		f: f,
+	}
+
+	return file, nil
+}
+
+// Module implements the Loader interface.
+func (l *overrideLoader) Module() *Module {
+	return l.ld.Module()
+}
+
+// glob implements the globber interface.
+func (l *overrideLoader) glob(pattern string) ([]string, error) {
+	names, err := Glob(l.ld, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if l.over == nil || !fsutil.IsDir(l.over.root) {
+		return names, nil
+	}
+
+	onames, err := Glob(l.over, pattern)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		seen[name] = true
+	}
+	for _, name := range onames {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// overrideFile represents a file that may be overridden by the user.
+type overrideFile struct {
+	l *overrideLoader
+	f File // the module data file
+
+	once  sync.Once
+	sf    File   // the selected file
+	layer string // the layer of sf
+	err   error
+}
+
+// Name implements the File interface.
+func (f *overrideFile) Name() string {
+	return f.f.Name()
+}
+
+// Path implements the File interface.
+func (f *overrideFile) Path() string {
+	f.once.Do(f.resolve)
+	if f.err != nil {
+		return ""
+	}
+
+	return f.sf.Path()
+}
+
+// Lstat implements the File interface.
+func (f *overrideFile) Lstat() (os.FileInfo, error) {
+	f.once.Do(f.resolve)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return f.sf.Lstat()
+}
+
+// Open implements the File interface.
+func (f *overrideFile) Open() (io.ReadCloser, error) {
+	f.once.Do(f.resolve)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return f.sf.Open()
+}
+
+// OpenRandom implements the RandomFile interface.
+func (f *overrideFile) OpenRandom() (RandomReader, error) {
+	f.once.Do(f.resolve)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return OpenRandom(f.sf)
+}
+
+// Layer returns the layer that served the file, LayerModule or
+// LayerOverride.
+func (f *overrideFile) Layer() string {
+	f.once.Do(f.resolve)
+
+	return f.layer
+}
+
+// unwrap returns the selected file.
+func (f *overrideFile) unwrap() File {
+	f.once.Do(f.resolve)
+
+	return f.sf
+}
+
+// resolve selects the override, if it exists.
+func (f *overrideFile) resolve() {
+	f.sf, f.layer = f.f, LayerModule
+	if f.l.over == nil {
+		return
+	}
+
+	of, err := f.l.over.Load(f.f.Name())
+	if err != nil {
+		return
+	}
+	_, err = of.Lstat()
+	switch {
+	case err == nil:
+		f.sf, f.layer = of, LayerOverride
+	case !errors.Is(err, os.ErrNotExist):
+		f.err = err
+	}
+}
+
+// FileLayer returns the layer that served f: LayerOverride if f is a user
+// override loaded by a loader returned by NewOverrideLoader, and LayerModule
+// otherwise.
+func FileLayer(f File) string {
+	if lf, ok := f.(interface{ Layer() string }); ok {
+		return lf.Layer()
+	}
+
+	return LayerModule
+}
+
+// Eject copies the data file named by name, loaded by ld, to the overrides
+// directory of the module, so that the user can customize it.  It returns the
+// path of the copy.
+//
+// Eject reports an error if the override already exists.
+func Eject(ld Loader, name string) (string, error) {
+	if ol, ok := ld.(*overrideLoader); ok {
+		ld = ol.ld
+	}
+	dir, err := OverridesDir(ld.Module().Path)
+	if err != nil {
+		return "", err
+	}
+	if p := path.Clean(name); p == "." || p == ".." || path.IsAbs(p) ||
+		strings.HasPrefix(p, "../") {
+		err := fmt.Errorf("eject %s: invalid name", name)
+
+		return "", mkerr(ld, err)
+	}
+	dst := filepath.Join(dir, filepath.FromSlash(name))
+	if _, err := os.Lstat(dst); err == nil {
+		err := fmt.Errorf("eject %s: %w", name, os.ErrExist)
+
+		return "", mkerr(ld, err)
+	}
+
+	f, err := ld.Load(name)
+	if err != nil {
+		return "", err
+	}
+	fi, err := f.Lstat()
+	if err != nil {
+		return "", err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	if err := eject(dst, rc, fi.Mode().Perm()|0200); err != nil {
+		return "", mkerr(fileLocator(f), ld, f, "eject", err)
+	}
+
+	return dst, nil
+}
+
+// eject writes the content read from r to the file named by dst, with
+// permissions perm.
+func eject(dst string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	w, err := CreateAtomic(dst, perm)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if _, err := io.Copy(w, r); err != nil {
+		return err
+	}
+
+	return w.Commit()
+}
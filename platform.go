@@ -0,0 +1,227 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// platform.go source file implements a Loader that loads the platform
+// specific variants of the data files.
+
+package data
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"sync"
+)
+
+// impliedGOOS maps a GOOS to the GOOS it implies, as done by the go command
+// for the file name build constraints.
+var impliedGOOS = map[string]string{
+	"android": "linux",
+	"illumos": "solaris",
+	"ios":     "darwin",
+}
+
+// platformLoader implements a Loader that loads the platform specific
+// variants of the data files.
+type platformLoader struct {
+	ld       Loader
+	suffixes []string // file name suffixes, in lookup order
+}
+
+// NewPlatformLoader returns a Loader that wraps ld, so that Load("name")
+// loads the variant of the file specific to the goos and goarch platform,
+// when it exists, mirroring the file name build constraints used by the go
+// command.
+//
+// As an example, for linux/amd64, Load("icons/app.png") searches, in order:
+//
+//	icons/app_linux_amd64.png
+//	icons/app_linux.png
+//	icons/app_amd64.png
+//	linux_amd64/icons/app.png
+//	linux/icons/app.png
+//	amd64/icons/app.png
+//	icons/app.png
+//
+// An empty goos or goarch means runtime.GOOS or runtime.GOARCH.  Tools that
+// need the view of another platform can use a different goos and goarch.  If
+// ld is a platform loader, its platform is replaced.
+//
+// Names without an extension, like "LICENSE", and dot files, like
+// ".gitignore", have no variants.
+//
+// The variant is searched lazily, so that Load does not access the data
+// files.  Name reports the requested name, and Path the path of the variant.
+//
+// The platform loader is not used by default, since it may access the data
+// files more than once for each Load.  It must be explicitly requested:
+//
+//	ld, err := data.Locate()
+//	if err != nil {
+//		return err
+//	}
+//	ld = data.NewPlatformLoader(ld, "", "")
+func NewPlatformLoader(ld Loader, goos, goarch string) Loader {
+	if pl, ok := ld.(*platformLoader); ok {
+		ld = pl.ld
+	}
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+
+	oses := []string{goos}
+	if implied, ok := impliedGOOS[goos]; ok {
+		oses = append(oses, implied)
+	}
+	var suffixes []string
+	for _, name := range oses {
+		suffixes = append(suffixes, name+"_"+goarch, name)
+	}
+	suffixes = append(suffixes, goarch)
+
+	l := &platformLoader{
+		ld:       ld,
+		suffixes: suffixes,
+	}
+
+	return l
+}
+
+// Load implements the Loader interface.
+func (l *platformLoader) Load(name string) (File, error) {
+	f, err := l.ld.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &platformFile{
+		l: l,
+		f: f,
+	}
+
+	return file, nil
+}
+
+// Module implements the Loader interface.
+func (l *platformLoader) Module() *Module {
+	return l.ld.Module()
+}
+
+// glob implements the globber interface.  The names of the variants are
+// reported as they are.
+func (l *platformLoader) glob(pattern string) ([]string, error) {
+	return Glob(l.ld, pattern)
+}
+
+// variants returns the names of the variants of the file named by name, in
+// lookup order.  Names without an extension and dot files have no variants.
+func (l *platformLoader) variants(name string) []string {
+	name = path.Clean(name)
+	dir, file := path.Split(name)
+	ext := path.Ext(file)
+	if ext == "" || file[0] == '.' {
+		return nil
+	}
+	base := file[:len(file)-len(ext)]
+
+	names := make([]string, 0, 2*len(l.suffixes))
+	for _, suffix := range l.suffixes {
+		names = append(names, dir+base+"_"+suffix+ext)
+	}
+	for _, suffix := range l.suffixes {
+		names = append(names, suffix+"/"+name)
+	}
+
+	return names
+}
+
+// platformFile represents a file that may have platform specific variants.
+type platformFile struct {
+	l *platformLoader
+	f File // the file with the requested name
+
+	once sync.Once
+	vf   File // the variant file, or f
+	err  error
+}
+
+// Name implements the File interface.
+func (f *platformFile) Name() string {
+	return f.f.Name()
+}
+
+// Path implements the File interface.
+func (f *platformFile) Path() string {
+	f.once.Do(f.resolve)
+	if f.err != nil {
+		return ""
+	}
+
+	return f.vf.Path()
+}
+
+// Lstat implements the File interface.
+func (f *platformFile) Lstat() (os.FileInfo, error) {
+	f.once.Do(f.resolve)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return f.vf.Lstat()
+}
+
+// Open implements the File interface.
+func (f *platformFile) Open() (io.ReadCloser, error) {
+	f.once.Do(f.resolve)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return f.vf.Open()
+}
+
+// OpenRandom implements the RandomFile interface.
+func (f *platformFile) OpenRandom() (RandomReader, error) {
+	f.once.Do(f.resolve)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return OpenRandom(f.vf)
+}
+
+// unwrap returns the variant file.
+func (f *platformFile) unwrap() File {
+	f.once.Do(f.resolve)
+
+	return f.vf
+}
+
+// resolve searches the first variant that exists.
+func (f *platformFile) resolve() {
+	f.vf = f.f
+	for _, name := range f.l.variants(f.f.Name()) {
+		vf, err := f.l.ld.Load(name)
+		if err != nil {
+			continue
+		}
+
+		_, err = vf.Lstat()
+		switch {
+		case err == nil:
+			f.vf = vf
+
+			return
+		case !errors.Is(err, os.ErrNotExist):
+			f.err = err
+
+			return
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlatformLoaderOrder(t *testing.T) {
+	tests := []struct {
+		goos, goarch string
+		name         string
+		variants     []string // in lookup order, the last is name
+	}{
+		{"linux", "amd64", "icons/app.png", []string{
+			"icons/app_linux_amd64.png",
+			"icons/app_linux.png",
+			"icons/app_amd64.png",
+			"linux_amd64/icons/app.png",
+			"linux/icons/app.png",
+			"amd64/icons/app.png",
+			"icons/app.png",
+		}},
+		{"android", "arm64", "app.png", []string{
+			"app_android_arm64.png",
+			"app_android.png",
+			"app_linux_arm64.png",
+			"app_linux.png",
+			"app_arm64.png",
+			"android_arm64/app.png",
+			"android/app.png",
+			"linux_arm64/app.png",
+			"linux/app.png",
+			"arm64/app.png",
+			"app.png",
+		}},
+		{"windows", "386", "a/b/c.tar.gz", []string{
+			"a/b/c.tar_windows_386.gz",
+			"a/b/c.tar_windows.gz",
+			"a/b/c.tar_386.gz",
+			"windows_386/a/b/c.tar.gz",
+			"windows/a/b/c.tar.gz",
+			"386/a/b/c.tar.gz",
+			"a/b/c.tar.gz",
+		}},
+	}
+	for _, test := range tests {
+		// Each variant has its name as content, and it is removed after being
+		// loaded, so that the next one is loaded.
+		files := make(map[string]string)
+		for _, name := range test.variants {
+			files[name] = name
+		}
+		root := t.TempDir()
+		writeFiles(t, root, files)
+		ld := NewPlatformLoader(&fsLoader{
+			lc:   namedLocator("fs:gopath"),
+			mod:  &testInfo.Main,
+			root: root,
+		}, test.goos, test.goarch)
+
+		for _, want := range test.variants {
+			f, err := ld.Load(test.name)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", test.goos, test.goarch, err)
+			}
+			if name := f.Name(); name != test.name {
+				t.Errorf("%s/%s: got name %q, want %q", test.goos, test.goarch,
+					name, test.name)
+			}
+			path := filepath.Join(root, filepath.FromSlash(want))
+			if got := f.Path(); got != path {
+				t.Errorf("%s/%s: got path %q, want %q", test.goos, test.goarch,
+					got, path)
+			}
+			got, err := readAll(ld, test.name)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", test.goos, test.goarch, err)
+			}
+			if got != want {
+				t.Errorf("%s/%s: got %q, want %q", test.goos, test.goarch, got,
+					want)
+			}
+
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestPlatformLoaderNoVariants(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"LICENSE":                "LICENSE",
+		"LICENSE_linux":          "LICENSE_linux",
+		"linux/LICENSE":          "linux/LICENSE",
+		".gitignore":             ".gitignore",
+		"linux/.gitignore":       "linux/.gitignore",
+		"dir/.config.json":       "dir/.config.json",
+		"dir/.config_linux.json": "dir/.config_linux.json",
+		"linux/dir/.config.json": "linux/dir/.config.json",
+		"a.txt":                  "a.txt",
+		"linux/a.txt":            "linux/a.txt",
+	})
+	base := &fsLoader{
+		lc:   namedLocator("fs:gopath"),
+		mod:  &testInfo.Main,
+		root: root,
+	}
+
+	// A platform loader wrapping a platform loader replaces its platform.
+	ld := NewPlatformLoader(NewPlatformLoader(base, "darwin", "arm64"),
+		"linux", "amd64")
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"LICENSE", "LICENSE"},
+		{".gitignore", ".gitignore"},
+		{"dir/.config.json", "dir/.config.json"},
+		{"a.txt", "linux/a.txt"},
+		{"missing.txt", ""},
+	}
+	for _, test := range tests {
+		got, err := readAll(ld, test.name)
+		if test.want == "" {
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("%s: got error %v, want not exist", test.name, err)
+			}
+
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
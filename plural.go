@@ -0,0 +1,262 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// plural.go source file implements the evaluation of the gettext plural
+// form expressions.
+
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pluralExpr is a parsed plural form expression, using the C syntax, like
+// "n==1 ? 0 : n%10>=2 && n%10<=4 ? 1 : 2".
+type pluralExpr interface {
+	eval(n int) int
+}
+
+type (
+	pluralN     struct{}
+	pluralConst int
+	pluralNot   struct{ x pluralExpr }
+	pluralCond  struct{ cond, x, y pluralExpr }
+	pluralBinop struct {
+		op   string
+		x, y pluralExpr
+	}
+)
+
+func (pluralN) eval(n int) int {
+	return n
+}
+
+func (e pluralConst) eval(n int) int {
+	return int(e)
+}
+
+func (e pluralNot) eval(n int) int {
+	return bool2int(e.x.eval(n) == 0)
+}
+
+func (e pluralCond) eval(n int) int {
+	if e.cond.eval(n) != 0 {
+		return e.x.eval(n)
+	}
+
+	return e.y.eval(n)
+}
+
+func (e pluralBinop) eval(n int) int {
+	x := e.x.eval(n)
+	switch e.op {
+	case "||":
+		return bool2int(x != 0 || e.y.eval(n) != 0)
+	case "&&":
+		return bool2int(x != 0 && e.y.eval(n) != 0)
+	}
+
+	y := e.y.eval(n)
+	switch e.op {
+	case "==":
+		return bool2int(x == y)
+	case "!=":
+		return bool2int(x != y)
+	case "<":
+		return bool2int(x < y)
+	case "<=":
+		return bool2int(x <= y)
+	case ">":
+		return bool2int(x > y)
+	case ">=":
+		return bool2int(x >= y)
+	case "+":
+		return x + y
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	case "/", "%":
+		if y == 0 {
+			return 0
+		}
+		if e.op == "/" {
+			return x / y
+		}
+
+		return x % y
+	}
+	panicf("unknown operator %q", e.op)
+
+	return 0
+}
+
+func bool2int(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// pluralPrec is the precedence of the binary operators.
+var pluralPrec = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+// pluralParser is a recursive descent parser for plural form expressions.
+type pluralParser struct {
+	toks []string
+	pos  int
+}
+
+// parsePlural parses the plural form expression s.
+func parsePlural(s string) (pluralExpr, error) {
+	toks, err := pluralTokens(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pluralParser{toks: toks}
+	e, err := p.cond()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("plural expression %q: unexpected %q", s, p.toks[p.pos])
+	}
+
+	return e, nil
+}
+
+func (p *pluralParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+
+	return ""
+}
+
+func (p *pluralParser) next() string {
+	tok := p.peek()
+	p.pos++
+
+	return tok
+}
+
+// cond parses a conditional expression.
+func (p *pluralParser) cond() (pluralExpr, error) {
+	c, err := p.binary(1)
+	if err != nil || p.peek() != "?" {
+		return c, err
+	}
+	p.next()
+
+	x, err := p.cond()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.next(); tok != ":" {
+		return nil, fmt.Errorf("plural expression: expected ':', found %q", tok)
+	}
+	y, err := p.cond()
+	if err != nil {
+		return nil, err
+	}
+
+	return pluralCond{c, x, y}, nil
+}
+
+// binary parses a binary expression with operators of precedence at least
+// prec.
+func (p *pluralParser) binary(prec int) (pluralExpr, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		opPrec, ok := pluralPrec[op]
+		if !ok || opPrec < prec {
+			return x, nil
+		}
+		p.next()
+
+		y, err := p.binary(opPrec + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = pluralBinop{op, x, y}
+	}
+}
+
+// unary parses a unary expression.
+func (p *pluralParser) unary() (pluralExpr, error) {
+	switch tok := p.next(); {
+	case tok == "n":
+		return pluralN{}, nil
+	case tok == "!":
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return pluralNot{x}, nil
+	case tok == "(":
+		x, err := p.cond()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok != ")" {
+			return nil, fmt.Errorf("plural expression: expected ')', found %q", tok)
+		}
+
+		return x, nil
+	case tok != "" && tok[0] >= '0' && tok[0] <= '9':
+		var v int
+		for _, c := range tok {
+			v = v*10 + int(c-'0')
+		}
+
+		return pluralConst(v), nil
+	default:
+		return nil, fmt.Errorf("plural expression: unexpected %q", tok)
+	}
+}
+
+// pluralTokens splits the plural form expression s into tokens.
+func pluralTokens(s string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		case i+1 < len(s) && pluralPrec[s[i:i+2]] > 0:
+			// Two characters operator.
+			toks = append(toks, s[i:i+2])
+			i += 2
+		case strings.IndexByte("n!()?:<>+-*/%", c) >= 0:
+			toks = append(toks, s[i:i+1])
+			i++
+		default:
+			return nil, fmt.Errorf("plural expression %q: invalid character %q", s, c)
+		}
+	}
+
+	return toks, nil
+}
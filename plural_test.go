@@ -0,0 +1,80 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"testing"
+)
+
+// russian is the plural form expression used by the Russian catalogs.
+const russian = "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)"
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		expr string
+		n    int
+		want int
+	}{
+		// Precedence and associativity.
+		{"n+2*3", 1, 7},
+		{"(n+2)*3", 1, 9},
+		{"10-n-3", 2, 5},
+		{"12/n/2", 3, 2},
+		{"n%3*2", 5, 4},
+		{"n==1 || n==2 && n!=2", 1, 1},
+		{"n==1 || n==2 && n!=2", 2, 0},
+		{"n<2 == n<3", 2, 0},
+		{"!n", 0, 1},
+		{"!n==0", 3, 1},
+		{"!!n", 5, 1},
+
+		// Conditional chains.
+		{"n==0 ? 0 : n==1 ? 1 : 2", 0, 0},
+		{"n==0 ? 0 : n==1 ? 1 : 2", 1, 1},
+		{"n==0 ? 0 : n==1 ? 1 : 2", 5, 2},
+		{"n ? n>1 ? 2 : 1 : 0", 3, 2},
+		{russian, 1, 0},
+		{russian, 3, 1},
+		{russian, 11, 2},
+		{russian, 21, 0},
+		{russian, 112, 2},
+
+		// Division by zero.
+		{"n/0", 5, 0},
+		{"n%0", 5, 0},
+		{"n/(n-5)", 5, 0},
+		{"n%(n-5)", 5, 0},
+	}
+	for _, test := range tests {
+		e, err := parsePlural(test.expr)
+		if err != nil {
+			t.Errorf("parsePlural(%q): %v", test.expr, err)
+
+			continue
+		}
+		if got := e.eval(test.n); got != test.want {
+			t.Errorf("%s with n=%d: got %d, want %d", test.expr, test.n, got,
+				test.want)
+		}
+	}
+}
+
+func TestPluralErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"n+",
+		"(n",
+		"n ? 1",
+		"n ? 1 : ",
+		"n $ 1",
+		"1 2",
+		"n)",
+	}
+	for _, expr := range tests {
+		if _, err := parsePlural(expr); err == nil {
+			t.Errorf("parsePlural(%q): expected error", expr)
+		}
+	}
+}
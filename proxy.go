@@ -0,0 +1,522 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// proxy.go source file implements the "proxy" locator.
+
+package data
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/perillo/data/internal/fsutil"
+	"github.com/perillo/data/internal/gocmd"
+)
+
+// errNotFound is returned by a proxy when a module is not available.
+var errNotFound = errors.New("not found")
+
+// proxyLocator implements the "proxy" locator that locates a module by
+// downloading it from the Go module proxy.
+type proxyLocator struct {
+	once    sync.Once
+	err     error    // error from configure
+	proxies []proxy  // proxy list from $GOPROXY
+	private []string // patterns from $GONOPROXY or $GOPRIVATE
+	cache   string   // absolute path to the cache directory
+	client  *http.Client
+
+	mu    sync.Mutex
+	calls map[string]*proxyCall // in flight downloads, by directory
+}
+
+// proxyCall is an in flight download.
+type proxyCall struct {
+	wg  sync.WaitGroup
+	err error
+}
+
+// proxymode enables the downloads from the module proxy by the default
+// locator, when set to "on" at build time with:
+//
+//	-ldflags=-X=github.com/perillo/data.proxymode=on
+//
+// Since the "proxy" locator accesses the network, it is not used by the
+// default locator unless enabled.
+var proxymode string
+
+// proxyLoc is the "proxy" locator, used by the default locator.
+var proxyLoc = newProxyLocator()
+
+// proxy is an entry in the $GOPROXY list.
+type proxy struct {
+	url      string
+	fallback bool // try the next proxy on any error, not only errNotFound
+}
+
+// newProxyLocator returns a new "proxy" locator, for modules downloaded from
+// the Go module proxy.
+//
+// The module zip file is downloaded on demand, honoring $GOPROXY, $GONOPROXY
+// and $GOPRIVATE, and it is verified against the module checksum reported by
+// build info.  Only the data directory is extracted and stored in the user
+// cache directory.
+//
+// Concurrent downloads of the same module are coalesced, and the downloads
+// time out if the proxy stalls.
+//
+// The locator is disabled if $GOFLAGS contains -mod=vendor.  Since the
+// configuration is read lazily, newProxyLocator never returns the "null"
+// locator.
+func newProxyLocator() *proxyLocator {
+	l := &proxyLocator{
+		client: httpClient,
+	}
+
+	return l
+}
+
+// Locate implements the Locator interface.
+func (l *proxyLocator) Locate(modpath string) (Loader, error) {
+	ld, err := l.locate(modpath)
+	if err != nil {
+		return nil, mkerr(l, err)
+	}
+
+	return ld, nil
+}
+
+func (l *proxyLocator) locate(modpath string) (Loader, error) {
+	mod, err := l.check(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	epath, err := escape(mod.Path)
+	if err != nil {
+		return nil, err
+	}
+	eversion, err := escape(mod.Version)
+	if err != nil {
+		return nil, err
+	}
+	dirpath := filepath.Join(l.cache, filepath.FromSlash(epath)+"@"+eversion)
+	if err := l.extract(dirpath, mod, epath+"/@v/"+eversion+".zip"); err != nil {
+		return nil, err
+	}
+
+	// It is responsibility of Loader to report an error if the data directory
+	// does not exists.
+	ld := &fsLoader{
+		lc:   l,
+		mod:  mod,
+		root: filepath.Join(dirpath, "data"),
+	}
+
+	return ld, nil
+}
+
+// Name implements the Locator interface.
+func (l *proxyLocator) Name() string {
+	return "proxy"
+}
+
+// fallbackLocator is a locator that downloads from the module proxy the
+// modules not located by the wrapped locator.
+type fallbackLocator struct {
+	l     Locator
+	proxy *proxyLocator
+}
+
+// Locate implements the Locator interface.
+func (l *fallbackLocator) Locate(modpath string) (Loader, error) {
+	ld, err := l.l.Locate(modpath)
+	if err == nil || !l.proxy.usable(modpath) {
+		return ld, err
+	}
+
+	return l.proxy.Locate(modpath)
+}
+
+// Name implements the Locator interface.  It returns the name of the wrapped
+// locator.
+func (l *fallbackLocator) Name() string {
+	return l.l.Name()
+}
+
+// check returns the module named by modpath, if it can be downloaded from the
+// module proxy, without accessing the network.
+func (l *proxyLocator) check(modpath string) (*Module, error) {
+	l.once.Do(func() {
+		l.err = l.configure()
+	})
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	// Find module in build info.
+	mod, err := find(modpath)
+	if err != nil {
+		return nil, err
+	}
+	if matchPrivate(l.private, mod.Path) {
+		return nil, fmt.Errorf("module %s is private", mod.Path)
+	}
+	if mod.Sum == "" {
+		return nil, fmt.Errorf("module %s has no checksum", mod)
+	}
+
+	return mod, nil
+}
+
+// usable returns true if the module named by modpath can be downloaded from
+// the module proxy.
+func (l *proxyLocator) usable(modpath string) bool {
+	_, err := l.check(modpath)
+
+	return err == nil
+}
+
+// extract extracts the data of the module mod in dirpath, downloading the
+// module zip file from the proxy list if necessary.  Only one download for
+// the same module is in flight at a given time; concurrent callers wait for
+// the download to complete, without blocking the downloads of other modules.
+func (l *proxyLocator) extract(dirpath string, mod *Module, file string) error {
+	if fsutil.IsDir(dirpath) {
+		return nil
+	}
+
+	l.mu.Lock()
+	if call, ok := l.calls[dirpath]; ok {
+		l.mu.Unlock()
+		call.wg.Wait()
+
+		return call.err
+	}
+	call := new(proxyCall)
+	call.wg.Add(1)
+	if l.calls == nil {
+		l.calls = make(map[string]*proxyCall)
+	}
+	l.calls[dirpath] = call
+	l.mu.Unlock()
+
+	// The data may have been extracted by a call that completed after the
+	// first check.
+	if !fsutil.IsDir(dirpath) {
+		call.err = l.download(dirpath, mod, file)
+	}
+	call.wg.Done()
+
+	l.mu.Lock()
+	delete(l.calls, dirpath)
+	l.mu.Unlock()
+
+	return call.err
+}
+
+// configure reads the proxy configuration from the environment.
+func (l *proxyLocator) configure() error {
+	if modFlag(goenv("GOFLAGS")) == "vendor" {
+		return errors.New("module downloads are disabled by -mod=vendor")
+	}
+
+	value := goenv("GOPROXY")
+	if value == "" {
+		value = "https://proxy.golang.org,direct"
+	}
+	for value != "" {
+		var (
+			entry    string
+			fallback bool
+		)
+		if idx := strings.IndexAny(value, ",|"); idx >= 0 {
+			entry, fallback, value = value[:idx], value[idx] == '|', value[idx+1:]
+		} else {
+			entry, value = value, ""
+		}
+
+		switch entry = strings.TrimSpace(entry); entry {
+		case "":
+			continue
+		case "off":
+			// Stop at the first "off" entry.
+			value = ""
+
+			continue
+		case "direct":
+			// Direct access to the version control repositories is not
+			// supported.
+			continue
+		}
+		p := proxy{
+			url:      strings.TrimSuffix(entry, "/"),
+			fallback: fallback,
+		}
+		l.proxies = append(l.proxies, p)
+	}
+	if len(l.proxies) == 0 {
+		return errors.New("no module proxy is available")
+	}
+
+	private := goenv("GONOPROXY")
+	if private == "" {
+		private = goenv("GOPRIVATE")
+	}
+	for _, pattern := range strings.Split(private, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			l.private = append(l.private, pattern)
+		}
+	}
+
+	if l.cache == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return err
+		}
+		l.cache = filepath.Join(dir, "go-data", "proxy")
+	}
+
+	return nil
+}
+
+// download downloads the module zip file from the proxy list, verifies it and
+// extracts the data directory in dirpath.
+func (l *proxyLocator) download(dirpath string, mod *Module, file string) error {
+	tmp, err := os.CreateTemp("", "go-data-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	for _, p := range l.proxies {
+		if err = fetchZip(l.client, tmp, p.url+"/"+file); err == nil {
+			break
+		}
+		if err != errNotFound && !p.fallback {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("module %s: %v", mod, err)
+	}
+
+	size, err := tmp.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return err
+	}
+	sum, err := hashZip(zr)
+	if err != nil {
+		return err
+	}
+	if sum != mod.Sum {
+		return fmt.Errorf("module %s: checksum mismatch: downloaded %s, "+
+			"build info %s", mod, sum, mod.Sum)
+	}
+
+	return extractData(dirpath, zr, mod.String()+"/data/")
+}
+
+// fetchZip writes to f the content of the module zip file at url, using
+// client for the http and https URLs.
+func fetchZip(client *http.Client, f *os.File, url string) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+
+	var (
+		rc  io.ReadCloser
+		err error
+	)
+	if strings.HasPrefix(url, "file://") {
+		rc, err = os.Open(fileURLPath(url))
+		if os.IsNotExist(err) {
+			return errNotFound
+		}
+	} else {
+		var resp *http.Response
+
+		resp, err = client.Get(url)
+		if err == nil {
+			rc = resp.Body
+			switch resp.StatusCode {
+			case http.StatusOK:
+			case http.StatusNotFound, http.StatusGone:
+				rc.Close()
+
+				return errNotFound
+			default:
+				rc.Close()
+
+				return fmt.Errorf("GET %s: %s", url, resp.Status)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(f, rc)
+
+	return err
+}
+
+// fileURLPath returns the local path of the file URL u.  On Windows, the
+// leading slash before the drive letter is removed, so that
+// file:///C:/proxy is converted to C:\proxy.
+func fileURLPath(u string) string {
+	p := strings.TrimPrefix(u, "file://")
+	if len(p) >= 3 && p[0] == '/' && p[2] == ':' && filepath.VolumeName(p[1:]) != "" {
+		p = p[1:]
+	}
+
+	return filepath.FromSlash(p)
+}
+
+// hashZip returns the "h1:" hash of the module zip file, as computed by the go
+// command.
+func hashZip(zr *zip.Reader) (string, error) {
+	files := make([]*zip.File, len(zr.File))
+	copy(files, zr.File)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	h := sha256.New()
+	for _, zf := range files {
+		if strings.Contains(zf.Name, "\n") {
+			return "", errors.New("file names with new lines are not supported")
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return "", err
+		}
+		hf := sha256.New()
+		_, err = io.Copy(hf, rc)
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%x  %s\n", hf.Sum(nil), zf.Name)
+	}
+
+	return "h1:" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// extractData extracts the files with the specified prefix in zr to dirpath,
+// atomically.
+func extractData(dirpath string, zr *zip.Reader, prefix string) error {
+	if err := os.MkdirAll(filepath.Dir(dirpath), 0777); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dirpath), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, zf := range zr.File {
+		if !strings.HasPrefix(zf.Name, prefix) {
+			continue
+		}
+		name := path.Clean(zf.Name[len(prefix):])
+		if strings.HasPrefix(name, "../") || zf.Mode().IsDir() {
+			continue
+		}
+
+		dst := filepath.Join(tmp, "data", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+			return err
+		}
+		if err := extractFile(dst, zf); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp, dirpath); err != nil && !fsutil.IsDir(dirpath) {
+		// Report the error only if the data has not been extracted by another
+		// process.
+		return err
+	}
+
+	return nil
+}
+
+// extractFile extracts the file zf to dst.
+func extractFile(dst string, zf *zip.File) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, rc); err != nil {
+		w.Close()
+
+		return err
+	}
+
+	return w.Close()
+}
+
+// matchPrivate returns true if modpath matches one of the patterns, as
+// specified for $GOPRIVATE.
+func matchPrivate(patterns []string, modpath string) bool {
+	n := strings.Count(modpath, "/") + 1
+	for _, pattern := range patterns {
+		// The pattern matches a prefix of modpath, with the same number of
+		// path elements.
+		m := strings.Count(pattern, "/") + 1
+		if m > n {
+			continue
+		}
+		prefix := modpath
+		for i := n; i > m; i-- {
+			prefix = prefix[:strings.LastIndexByte(prefix, '/')]
+		}
+		if ok, _ := path.Match(pattern, prefix); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// goenv returns the value of the named Go environment variable.  The value is
+// read from the environment, and from the go command if not set.
+func goenv(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	// The go command may not be available.
+	value, _ := gocmd.Getenv(key)
+
+	return value
+}
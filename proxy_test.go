@@ -0,0 +1,307 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// makeZip returns a zip archive with the specified files.
+func makeZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// setDeps sets the module dependencies in build info, for the duration of the
+// test t.
+func setDeps(t *testing.T, deps ...Module) {
+	old := loadInfo()
+	bi := *old
+	bi.Deps = deps
+	storeInfo(&bi)
+	t.Cleanup(func() {
+		storeInfo(old)
+	})
+}
+
+// setProxy stores content as the v1.0.0 zip file of the modules named by
+// modpaths in a local file:// proxy, and sets $GOPROXY for the duration of the
+// test t.  It returns the proxy directory.
+func setProxy(t *testing.T, content []byte, modpaths ...string) string {
+	dir := t.TempDir()
+	for _, modpath := range modpaths {
+		zippath := filepath.Join(dir, filepath.FromSlash(modpath), "@v", "v1.0.0.zip")
+		if err := os.MkdirAll(filepath.Dir(zippath), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(zippath, content, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proxyURL := "file://" + filepath.ToSlash(dir)
+	if !strings.HasPrefix(proxyURL, "file:///") {
+		// A Windows path, like C:/proxy.
+		proxyURL = "file:///" + strings.TrimPrefix(proxyURL, "file://")
+	}
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", proxyURL)
+
+	return dir
+}
+
+func TestHashZip(t *testing.T) {
+	tests := []struct {
+		files map[string]string
+		want  string
+	}{
+		{
+			map[string]string{},
+			"h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+		},
+		{
+			map[string]string{
+				"example.com/m@v1.0.0/go.mod": "module example.com/m\n",
+			},
+			"h1:yJwNngL0tCKlmRg8yireic46hRGohEbhwD/WSE0Ax3I=",
+		},
+		{
+			// The files are sorted by name.
+			map[string]string{
+				"example.com/m@v1.0.0/b.txt": "b",
+				"example.com/m@v1.0.0/a.txt": "a",
+			},
+			"h1:NZIJvnhe2IDwvoMy2Zaj9osijqPu8sjXr2dqF90pmAw=",
+		},
+	}
+	for _, test := range tests {
+		buf := makeZip(t, test.files)
+		zr, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := hashZip(zr)
+		if err != nil {
+			t.Fatalf("hashZip(%v): %v", test.files, err)
+		}
+		if got != test.want {
+			t.Errorf("hashZip(%v): got %s, want %s", test.files, got, test.want)
+		}
+	}
+}
+
+func TestMatchPrivate(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		modpath  string
+		want     bool
+	}{
+		{nil, "example.com/m", false},
+		{[]string{"example.com"}, "example.com", true},
+		{[]string{"example.com"}, "example.com/m", true},
+		{[]string{"example.com"}, "example.org/m", false},
+		{[]string{"example.com/m"}, "example.com/m/v2", true},
+		{[]string{"example.com/m"}, "example.com/mm", false},
+		{[]string{"example.com/m/sub"}, "example.com/m", false},
+		{[]string{"*.corp.example.com"}, "git.corp.example.com/m", true},
+		{[]string{"*.corp.example.com"}, "corp.example.com/m", false},
+		{[]string{"example.com/*"}, "example.com/m/sub", true},
+		{[]string{"example.org", "example.com"}, "example.com/m", true},
+	}
+	for _, test := range tests {
+		got := matchPrivate(test.patterns, test.modpath)
+		if got != test.want {
+			t.Errorf("matchPrivate(%q, %q): got %v, want %v",
+				test.patterns, test.modpath, got, test.want)
+		}
+	}
+}
+
+func TestFileURLPath(t *testing.T) {
+	got := fileURLPath("file:///srv/proxy")
+	if want := filepath.FromSlash("/srv/proxy"); got != want {
+		t.Errorf("fileURLPath: got %q, want %q", got, want)
+	}
+}
+
+func TestProxyLocator(t *testing.T) {
+	content := makeZip(t, map[string]string{
+		"example.com/m@v1.0.0/go.mod":     "module example.com/m\n",
+		"example.com/m@v1.0.0/data/a.txt": "a",
+	})
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := hashZip(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The module zip file is stored in a local file:// proxy.
+	dir := setProxy(t, content, "example.com/m", "example.com/bad")
+	t.Setenv("GONOPROXY", "example.com/private")
+
+	setDeps(t,
+		Module{Path: "example.com/m", Version: "v1.0.0", Sum: sum},
+		Module{Path: "example.com/bad", Version: "v1.0.0", Sum: "h1:bad"},
+		Module{Path: "example.com/missing", Version: "v1.0.0", Sum: sum},
+		Module{Path: "example.com/private", Version: "v1.0.0", Sum: sum},
+	)
+	l := &proxyLocator{
+		cache:  t.TempDir(),
+		client: httpClient,
+	}
+
+	// Concurrent lookups of the same module are coalesced.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ld, err := l.Locate("example.com/m")
+			if err != nil {
+				t.Error(err)
+
+				return
+			}
+			if got, err := readAll(ld, "a.txt"); err != nil {
+				t.Error(err)
+			} else if got != "a" {
+				t.Errorf("a.txt: got %q, want %q", got, "a")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The zip file is corrupted, to check that the module is not downloaded
+	// again.
+	zippath := filepath.Join(dir, "example.com", "m", "@v", "v1.0.0.zip")
+	if err := os.WriteFile(zippath, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Locate("example.com/m"); err != nil {
+		t.Errorf("cached module: %v", err)
+	}
+
+	tests := []struct {
+		modpath string
+		want    string // error substring
+	}{
+		{"example.com/bad", "checksum mismatch"},
+		{"example.com/missing", "not found"},
+		{"example.com/private", "is private"},
+	}
+	for _, test := range tests {
+		_, err := l.Locate(test.modpath)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Locate(%q): got error %v, want %q", test.modpath, err,
+				test.want)
+		}
+	}
+}
+
+func TestFallbackLocator(t *testing.T) {
+	content := makeZip(t, map[string]string{
+		"example.com/m@v1.0.0/go.mod":     "module example.com/m\n",
+		"example.com/m@v1.0.0/data/a.txt": "proxy",
+	})
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := hashZip(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setProxy(t, content, "example.com/m", "example.com/local")
+
+	setDeps(t,
+		Module{Path: "example.com/local", Version: "v1.0.0", Sum: sum},
+		Module{Path: "example.com/m", Version: "v1.0.0", Sum: sum},
+		Module{Path: "example.com/nosum", Version: "v1.0.0"},
+	)
+
+	// The main module and example.com/local are relative to the executable.
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"data/a.txt": "main",
+		"go-data/example.com.local@v1.0.0/data/a.txt": "local",
+	})
+	l := &fallbackLocator{
+		l: NewExeLocator(root),
+		proxy: &proxyLocator{
+			cache:  t.TempDir(),
+			client: httpClient,
+		},
+	}
+	if name := l.Name(); name != "fs:exe" {
+		t.Fatalf("Name: got %q, want %q", name, "fs:exe")
+	}
+
+	tests := []struct {
+		modpath string
+		want    string // content of a.txt, or error substring
+		locator string // locator of the loader
+	}{
+		{mainPath(), "main", "fs:exe"},
+		{"example.com/local", "local", "fs:exe"},
+		{"example.com/m", "proxy", "proxy"},
+		{"example.com/nosum", "not relative to the executable", ""},
+	}
+	for _, test := range tests {
+		ld, err := l.Locate(test.modpath)
+		if test.locator == "" {
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Locate(%q): got error %v, want %q", test.modpath, err,
+					test.want)
+			}
+
+			continue
+		}
+		if err != nil {
+			t.Errorf("Locate(%q): %v", test.modpath, err)
+
+			continue
+		}
+		f, err := ld.Load("a.txt")
+		if err != nil {
+			t.Errorf("Locate(%q): %v", test.modpath, err)
+
+			continue
+		}
+		if name := fileLocator(f).Name(); name != test.locator {
+			t.Errorf("Locate(%q): got locator %q, want %q", test.modpath, name,
+				test.locator)
+		}
+		if got, err := readAll(ld, "a.txt"); err != nil {
+			t.Errorf("Locate(%q): %v", test.modpath, err)
+		} else if got != test.want {
+			t.Errorf("Locate(%q): got %q, want %q", test.modpath, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// random.go source file implements the random access to data files.
+
+package data
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// RandomReader provides random access to the content of a data file.
+type RandomReader interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+
+	// Size returns the size of the file content.
+	Size() int64
+}
+
+// RandomFile is implemented by the files that natively support random
+// access.  It is implemented by the files on the local filesystem, and by
+// the files in a tar archive or stored uncompressed in a zip archive.
+type RandomFile interface {
+	File
+
+	// OpenRandom provides random access to the data within a regular file.
+	OpenRandom() (RandomReader, error)
+}
+
+// OpenRandom provides random access to the data within the regular file f.  If
+// f does not implement RandomFile, the file content is read in memory.
+func OpenRandom(f File) (RandomReader, error) {
+	if rf, ok := f.(RandomFile); ok {
+		return rf.OpenRandom()
+	}
+
+	return readRandom(f)
+}
+
+// OpenMapped provides random access to the data within the regular file f,
+// memory mapping the file in read only mode if it is stored on the local
+// filesystem and the system supports it.  Otherwise it is the same as
+// OpenRandom.
+//
+// The file content must not be modified while it is mapped, and the data
+// returned by the reader must not be used after Close.
+func OpenMapped(f File) (RandomReader, error) {
+	if ff, ok := underlying(f).(*fsFile); ok {
+		return ff.openMapped()
+	}
+
+	return OpenRandom(f)
+}
+
+// underlying returns the file wrapped by f, if f is a wrapper that gives
+// access to the underlying file, like the files returned by
+// NewPlatformLoader.  Otherwise it returns f.
+func underlying(f File) File {
+	for {
+		u, ok := f.(interface{ unwrap() File })
+		if !ok {
+			return f
+		}
+		uf := u.unwrap()
+		if uf == nil {
+			return f
+		}
+		f = uf
+	}
+}
+
+// errNoMmap is returned by mmap when memory mapping is not supported.
+var errNoMmap = errors.New("memory mapping is not supported")
+
+// readRandom reads the content of the file f in memory, and provides random
+// access to it.  If the reader returned by f.Open is a RandomReader, it is
+// returned as is.
+func readRandom(f File) (RandomReader, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := rc.(RandomReader); ok {
+		return r, nil
+	}
+	defer rc.Close()
+
+	buf, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	return nopCloser{bytes.NewReader(buf)}, nil
+}
+
+// randomReader is a RandomReader without the Close method.
+type randomReader interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+
+	Size() int64
+}
+
+// nopCloser is a RandomReader with a no-op Close method.  It is used for
+// bytes.Reader and io.SectionReader.
+type nopCloser struct {
+	randomReader
+}
+
+// Close implements the io.Closer interface.
+func (nopCloser) Close() error {
+	return nil
+}
+
+// fileReader is a RandomReader for a file on the local filesystem.
+type fileReader struct {
+	*os.File
+	size int64
+}
+
+// openFile opens the file at path, for random access.
+func openFile(path string) (RandomReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		file.Close()
+
+		return nil, errors.New("not a regular file")
+	}
+
+	return &fileReader{file, fi.Size()}, nil
+}
+
+// Size implements the RandomReader interface.
+func (r *fileReader) Size() int64 {
+	return r.size
+}
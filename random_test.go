@@ -0,0 +1,204 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// readerType returns the type of the RandomReader r, or of the reader wrapped
+// by r if r is a nopCloser.
+func readerType(r RandomReader) string {
+	if nc, ok := r.(nopCloser); ok {
+		return fmt.Sprintf("%T", nc.randomReader)
+	}
+
+	return fmt.Sprintf("%T", r)
+}
+
+// checkRandom checks that r provides random access to content.
+func checkRandom(r RandomReader, content string) error {
+	if size := r.Size(); size != int64(len(content)) {
+		return fmt.Errorf("got size %d, want %d", size, len(content))
+	}
+	if len(content) == 0 {
+		return nil
+	}
+
+	// Read the second half, then the first one.
+	half := len(content) / 2
+	buf := make([]byte, len(content)-half)
+	if _, err := r.ReadAt(buf, int64(half)); err != nil && err != io.EOF {
+		return err
+	}
+	if got, want := string(buf), content[half:]; got != want {
+		return fmt.Errorf("ReadAt: got %q, want %q", got, want)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	buf = make([]byte, half)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return err
+	}
+	if got, want := string(buf), content[:half]; got != want {
+		return fmt.Errorf("Read: got %q, want %q", got, want)
+	}
+
+	return nil
+}
+
+func TestOpenRandom(t *testing.T) {
+	setInfo(t, testInfo)
+
+	const content = "0123456789"
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"data/a.txt":     content,
+		"data/empty.txt": "",
+	})
+	fsld := &fsLoader{
+		lc:   namedLocator("fs:gopath"),
+		mod:  &testInfo.Main,
+		root: filepath.Join(root, "data"),
+	}
+
+	// The zip archive has a stored and a compressed file.
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, h := range []*zip.FileHeader{
+		{Name: "app/data/stored.txt", Method: zip.Store},
+		{Name: "app/data/deflated.txt", Method: zip.Deflate},
+	} {
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zippath := filepath.Join(root, "data.zip")
+	if err := os.WriteFile(zippath, buf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+	zipld, err := NewZipLocator(zippath).Locate(testInfo.Main.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Memory mapping is only supported on Linux; on the other systems
+	// OpenMapped falls back to OpenRandom.
+	mapped, mappedEmpty := "*data.mmapReader", "*bytes.Reader"
+	if runtime.GOOS != "linux" {
+		mapped, mappedEmpty = "*data.fileReader", "*data.fileReader"
+	}
+
+	tests := []struct {
+		desc    string
+		ld      Loader
+		name    string
+		content string
+		random  string // type of the reader returned by OpenRandom
+		mapped  string // type of the reader returned by OpenMapped
+	}{
+		{"file", fsld, "a.txt", content, "*data.fileReader", mapped},
+		{"empty file", fsld, "empty.txt", "", "*data.fileReader", mappedEmpty},
+		{"wrapped file", NewPlatformLoader(fsld, "", ""), "a.txt", content,
+			"*data.fileReader", mapped},
+		{"not random file", newCountingLoader(t, map[string]string{
+			"a.txt": content,
+		}), "a.txt", content, "*bytes.Reader", "*bytes.Reader"},
+		{"stored zip file", zipld, "stored.txt", content,
+			"*io.SectionReader", "*io.SectionReader"},
+		{"deflated zip file", zipld, "deflated.txt", content,
+			"*bytes.Reader", "*bytes.Reader"},
+	}
+	for _, test := range tests {
+		f, err := test.ld.Load(test.name)
+		if err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+
+		for _, open := range []struct {
+			name string
+			fn   func(File) (RandomReader, error)
+			want string
+		}{
+			{"OpenRandom", OpenRandom, test.random},
+			{"OpenMapped", OpenMapped, test.mapped},
+		} {
+			r, err := open.fn(f)
+			if err != nil {
+				t.Errorf("%s: %s: %v", test.desc, open.name, err)
+
+				continue
+			}
+			if got := readerType(r); got != open.want {
+				t.Errorf("%s: %s: got reader %s, want %s", test.desc,
+					open.name, got, open.want)
+			}
+			if err := checkRandom(r, test.content); err != nil {
+				t.Errorf("%s: %s: %v", test.desc, open.name, err)
+			}
+			if err := r.Close(); err != nil {
+				t.Errorf("%s: %s: Close: %v", test.desc, open.name, err)
+			}
+		}
+	}
+}
+
+func TestOpenRandomErrors(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"dir/a.txt": "a",
+	})
+	ld := &fsLoader{
+		lc:   namedLocator("fs:gopath"),
+		mod:  &testInfo.Main,
+		root: root,
+	}
+
+	tests := []struct {
+		name string
+		want error // if not nil, the error must match
+	}{
+		{"missing.txt", os.ErrNotExist},
+		{"dir", nil},
+	}
+	for _, test := range tests {
+		f, err := ld.Load(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, open := range []func(File) (RandomReader, error){
+			OpenRandom, OpenMapped,
+		} {
+			r, err := open(f)
+			if err == nil {
+				r.Close()
+				t.Errorf("%s: got no error", test.name)
+
+				continue
+			}
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Errorf("%s: got error %T, want *Error", test.name, err)
+			}
+			if test.want != nil && !errors.Is(err, test.want) {
+				t.Errorf("%s: got error %v, want %v", test.name, err, test.want)
+			}
+		}
+	}
+}
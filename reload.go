@@ -0,0 +1,133 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// reload.go source file implements a Loader that reloads the data files when
+// they change.
+
+package data
+
+import "sync"
+
+// Reloader is a Loader that caches the file contents in memory, like the
+// loader returned by NewCachingLoader, and invalidates the cache when the
+// data files change, notifying the subscribers.
+//
+// Reloader is intended to be used during development, for example with the
+// "fs:gopath" locator, so that the changes to the data files are reflected
+// live.
+type Reloader struct {
+	ld *cacheLoader
+	w  *Watcher
+
+	mu   sync.Mutex
+	subs map[int]func(Event)
+	next int // next subscriber id
+}
+
+// NewReloader returns a new Reloader that wraps ld, caching the file contents
+// in memory with a maximum size of max bytes.  The data files are watched
+// with Watch.
+func NewReloader(ld Loader, max int64) (*Reloader, error) {
+	w, err := Watch(ld)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{
+		ld: &cacheLoader{
+			ld:    ld,
+			cache: newLRU(max),
+		},
+		w:    w,
+		subs: make(map[int]func(Event)),
+	}
+	go r.run()
+
+	return r, nil
+}
+
+// Load implements the Loader interface.
+func (r *Reloader) Load(path string) (File, error) {
+	return r.ld.Load(path)
+}
+
+// Module implements the Loader interface.
+func (r *Reloader) Module() *Module {
+	return r.ld.Module()
+}
+
+// glob implements the globber interface.
+func (r *Reloader) glob(pattern string) ([]string, error) {
+	return Glob(r.ld, pattern)
+}
+
+// Subscribe registers fn to be called for each change to the data files,
+// after the cache has been invalidated.  fn is called from a separate
+// goroutine, and it should not block.  The returned function cancels the
+// subscription.
+func (r *Reloader) Subscribe(fn func(Event)) (cancel func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.next
+	r.next++
+	r.subs[id] = fn
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		delete(r.subs, id)
+	}
+}
+
+// Close stops watching the data files.
+func (r *Reloader) Close() error {
+	return r.w.Close()
+}
+
+// run invalidates the cache and notifies the subscribers, until the watcher
+// is closed.
+func (r *Reloader) run() {
+	// All the cached files of the module are invalidated, since a file may be
+	// loaded using different paths, e.g. "a/b" and "a/./b".
+	prefix := r.ld.Module().String() + "\x00"
+
+	events, errors := r.w.Events, r.w.Errors
+	for events != nil || errors != nil {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+
+				continue
+			}
+			r.ld.cache.remove(prefix)
+			r.notify(ev)
+		case _, ok := <-errors:
+			if !ok {
+				errors = nil
+
+				continue
+			}
+
+			// Some events may have been lost.
+			r.ld.cache.remove(prefix)
+		}
+	}
+}
+
+// notify calls the subscribers with the event ev.
+func (r *Reloader) notify(ev Event) {
+	r.mu.Lock()
+	subs := make([]func(Event), 0, len(r.subs))
+	for _, fn := range r.subs {
+		subs = append(subs, fn)
+	}
+	r.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(ev)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReloader(t *testing.T) {
+	cl := newCountingLoader(t, map[string]string{
+		"a.txt": "version 1",
+	})
+	root := cl.Loader.(*fsLoader).root
+	r, err := NewReloader(cl, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	events := make(chan Event)
+	r.Subscribe(func(ev Event) {
+		events <- ev
+	})
+	var (
+		mu        sync.Mutex
+		cancelled int // events received after the cancellation
+	)
+	cancel := r.Subscribe(func(ev Event) {
+		mu.Lock()
+		cancelled++
+		mu.Unlock()
+	})
+	cancel()
+
+	// wait waits for the event for the file a.txt.  The subscribers are
+	// notified after the cache has been invalidated.
+	wait := func(desc string) {
+		timeout := time.After(watchTimeout)
+		for {
+			select {
+			case ev := <-events:
+				if ev.Name == "a.txt" {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("%s: timeout", desc)
+			}
+		}
+	}
+
+	tests := []struct {
+		desc  string
+		write string // new content, if not empty
+		want  string
+		count int // number of calls to Open
+	}{
+		{"first load", "", "version 1", 1},
+		{"cached", "", "version 1", 1},
+		{"modified", "version 2", "version 2", 2},
+		{"cached modified", "", "version 2", 2},
+		{"modified again", "version 3", "version 3", 3},
+	}
+	for _, test := range tests {
+		if test.write != "" {
+			path := filepath.Join(root, "a.txt")
+			if err := os.WriteFile(path, []byte(test.write), 0666); err != nil {
+				t.Fatal(err)
+			}
+			wait(test.desc)
+		}
+		got, err := readAll(r, "a.txt")
+		if err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.desc, got, test.want)
+		}
+		if count := cl.count("a.txt"); count != test.count {
+			t.Errorf("%s: got %d calls to Open, want %d", test.desc, count,
+				test.count)
+		}
+	}
+
+	// The cancelled subscriber is never notified.
+	mu.Lock()
+	defer mu.Unlock()
+	if cancelled != 0 {
+		t.Errorf("cancelled subscriber: got %d events, want 0", cancelled)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// self.go source file implements the loading of module data for the module
+// owning a package.
+
+package data
+
+import "sync"
+
+// Self returns the loader for the module containing the calling package,
+// using the default locator.
+//
+// The module is located on the first call to Load, and an error locating the
+// module is reported by Load.  This allows a library to load its own data
+// with:
+//
+//	f, err := data.Self().Load("x.json")
+func Self() Loader {
+	modpath, err := callerModule(1)
+
+	return &packageLoader{
+		modpath: modpath,
+		err:     err,
+	}
+}
+
+// ForPackage returns the loader for the module containing the package named
+// by pkgpath, using the default locator.  The module is the active module
+// whose path is the longest prefix of pkgpath.
+//
+// As with Self, the module is located on the first call to Load.
+func ForPackage(pkgpath string) Loader {
+	modpath, err := packageModule(pkgpath)
+
+	return &packageLoader{
+		modpath: modpath,
+		err:     err,
+	}
+}
+
+// packageLoader is a Loader that lazily locates the module owning a package.
+type packageLoader struct {
+	modpath string
+	err     error // error finding the module
+
+	once sync.Once
+	ld   Loader
+}
+
+// Load implements the Loader interface.
+func (l *packageLoader) Load(path string) (File, error) {
+	l.once.Do(l.locate)
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	return l.ld.Load(path)
+}
+
+// Module implements the Loader interface.
+//
+// If the module is not an active module, Module returns a module with only
+// the path set, possibly empty.
+func (l *packageLoader) Module() *Module {
+	l.once.Do(l.locate)
+	if l.ld != nil {
+		return l.ld.Module()
+	}
+
+	return &Module{
+		Path: l.modpath,
+	}
+}
+
+// glob implements the globber interface.
+func (l *packageLoader) glob(pattern string) ([]string, error) {
+	l.once.Do(l.locate)
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	return Glob(l.ld, pattern)
+}
+
+func (l *packageLoader) locate() {
+	if l.err != nil {
+		l.err = mkerr(DefaultLocator(), l.err)
+
+		return
+	}
+
+	l.ld, l.err = LocateModule(l.modpath)
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSelf(t *testing.T) {
+	setCallerModules(t)
+
+	tests := []struct {
+		desc string
+		ld   Loader
+		want string // content of a.txt, or error substring
+		mod  string // module path
+	}{
+		{"Self", Self(), "data", "github.com/perillo/data"},
+		{"ForPackage main", ForPackage("main"), "app", "example.com/app"},
+		{"ForPackage dependency", ForPackage("example.com/lib/sub"), "lib",
+			"example.com/lib"},
+		{"ForPackage not located", ForPackage("example.com/libx"),
+			"module example.com/libx not found", "example.com/libx"},
+		{"ForPackage not active", ForPackage("example.com/other"),
+			"not in an active module", ""},
+	}
+	for _, test := range tests {
+		got, err := readAll(test.ld, "a.txt")
+		if err != nil {
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Errorf("%s: got error %T, want *Error", test.desc, err)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("%s: got error %v, want %q", test.desc, err, test.want)
+			}
+		} else if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.desc, got, test.want)
+		}
+		if mod := test.ld.Module().Path; mod != test.mod {
+			t.Errorf("%s: got module %q, want %q", test.desc, mod, test.mod)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// state.go source file implements the writable per-user directories of a
+// module, and the atomic writing of files.
+
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// DirKind is the kind of a writable directory.
+type DirKind int
+
+// The kinds of writable directories.
+const (
+	DataDir   DirKind = iota // user data, in UserDataDir or $GODATA
+	ConfigDir                // configuration, in os.UserConfigDir
+	CacheDir                 // non essential cached data, in os.UserCacheDir
+	StateDir                 // state data, in UserStateDir
+)
+
+// String returns the name of the directory kind.
+func (k DirKind) String() string {
+	switch k {
+	case DataDir:
+		return "data"
+	case ConfigDir:
+		return "config"
+	case CacheDir:
+		return "cache"
+	case StateDir:
+		return "state"
+	}
+
+	return fmt.Sprintf("DirKind(%d)", int(k))
+}
+
+// WritableDir returns the writable per-user directory of the specified kind
+// for the module named by modpath, creating it if necessary.
+//
+// The directory uses the same layout as the user data directory: the main
+// module directory is $APPNAME, and the directory of the other active modules
+// is go-data/ with the fully versioned path flattened.  Since the data
+// directory of the module is used by the "fs:user" locator, the writable data
+// directory is the var subdirectory.
+func WritableDir(kind DirKind, modpath string) (string, error) {
+	dirpath, err := writableDir(kind, modpath)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dirpath, 0700); err != nil {
+		return "", fmt.Errorf("data: %s directory: %w", kind, err)
+	}
+
+	return dirpath, nil
+}
+
+// writableDir returns the writable directory of the specified kind for the
+// module named by modpath, without creating it.
+func writableDir(kind DirKind, modpath string) (string, error) {
+	info := loadInfo()
+	if info == nil {
+		return "", errors.New("data: build info is not available")
+	}
+	mod, err := find(modpath)
+	if err != nil {
+		return "", fmt.Errorf("data: %w", err)
+	}
+
+	var root string
+	switch kind {
+	case DataDir:
+		root, err = godata()
+	case ConfigDir:
+		root, err = os.UserConfigDir()
+	case CacheDir:
+		root, err = os.UserCacheDir()
+	case StateDir:
+		root, err = UserStateDir()
+	default:
+		err = fmt.Errorf("unknown directory kind %v", kind)
+	}
+	if err != nil {
+		return "", fmt.Errorf("data: %s directory: %w", kind, err)
+	}
+
+	var dirpath string
+	if modpath == info.Main.Path {
+		dirpath = filepath.Join(root, AppName())
+	} else {
+		dirpath = filepath.Join(root, "go-data", mod.FlatPath())
+	}
+	if kind == DataDir {
+		dirpath = filepath.Join(dirpath, "var")
+	}
+
+	return dirpath, nil
+}
+
+// WriteFileAtomic writes data to the file named by name, so that readers see
+// either the old or the new content, and never a partially written file.
+// Since the file is replaced, it has permissions perm (not modified by the
+// umask) even if it already exists.
+func WriteFileAtomic(name string, data []byte, perm os.FileMode) error {
+	f, err := CreateAtomic(name, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+
+	return f.Commit()
+}
+
+// AtomicFile is a file that replaces the file named by Name only when the
+// writing is complete and committed.
+type AtomicFile struct {
+	*os.File // the temporary file
+
+	name string
+	perm os.FileMode
+	done bool
+}
+
+// CreateAtomic creates a temporary file in the same directory of the file
+// named by name.  The file named by name is replaced by the temporary file,
+// with permissions perm (not modified by the umask), only when Commit is
+// called.
+//
+// Close must always be called, and it discards the temporary file if Commit
+// has not been called.
+func CreateAtomic(name string, perm os.FileMode) (*AtomicFile, error) {
+	dir, file := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+file+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	f := &AtomicFile{
+		File: tmp,
+		name: name,
+		perm: perm,
+	}
+
+	return f, nil
+}
+
+// Name returns the name of the file that will be replaced.
+func (f *AtomicFile) Name() string {
+	return f.name
+}
+
+// Commit flushes the content of the temporary file to stable storage, and
+// replaces the file named by Name with it.  The directory is flushed too, so
+// that the replacement survives a system crash.
+func (f *AtomicFile) Commit() error {
+	if f.done {
+		return &os.PathError{Op: "commit", Path: f.name, Err: os.ErrClosed}
+	}
+	f.done = true
+
+	tmp := f.File.Name()
+	err := f.File.Sync()
+	if cerr := f.File.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, f.perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, f.name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+
+		return err
+	}
+
+	return syncDir(filepath.Dir(f.name))
+}
+
+// Close discards the temporary file, if Commit has not been called.
+// Otherwise it does nothing.
+func (f *AtomicFile) Close() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	err := f.File.Close()
+	os.Remove(f.File.Name())
+
+	return err
+}
+
+// syncDir flushes the directory at path to stable storage.  It does nothing
+// on Windows, where directories can not be flushed.
+func syncDir(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
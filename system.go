@@ -0,0 +1,102 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// system.go source file implements the "fs:system" locator.
+
+package data
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/perillo/data/internal/fsutil"
+)
+
+// systemLocator implements the "fs:system" locator that locates a module in
+// the system data directories.
+type systemLocator struct {
+	pathList []string
+}
+
+// newSystemLocator returns a new "fs:system" locator, for modules in the
+// system data directories.
+//
+// newSystemLocator returns the "null" locator if the system data directories
+// are not available or the application is not stored in any of the system
+// data directories.
+func newSystemLocator() Locator {
+	info := loadInfo()
+
+	dirs, err := SystemDataDirs()
+	if err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	l := &systemLocator{
+		pathList: dirs,
+	}
+
+	// Check if the main module is in the system data directories.
+	if _, err := l.locate(info.Main.Path); err != nil {
+		return &nullLocator{
+			err: fmt.Errorf("main module %s is not in the system data directories",
+				&info.Main),
+		}
+	}
+
+	return l
+}
+
+// Locate implements the Locator interface.
+func (l *systemLocator) Locate(modpath string) (Loader, error) {
+	ld, err := l.locate(modpath)
+	if err != nil {
+		return nil, mkerr(l, err)
+	}
+
+	return ld, nil
+}
+
+func (l *systemLocator) locate(modpath string) (Loader, error) {
+	info := loadInfo()
+
+	// Find module in build info.
+	mod, err := find(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Search the module in the system data directories, using the same layout
+	// as the "fs:user" locator.
+	for _, root := range l.pathList {
+		var dirpath string
+		if modpath == info.Main.Path {
+			dirpath = filepath.Join(root, AppName())
+		} else {
+			dirpath = filepath.Join(root, "go-data", mod.FlatPath())
+		}
+
+		if fsutil.IsDir(dirpath) {
+			// It is responsibility of Loader to report an error if the data
+			// directory does not exists.
+			ld := &fsLoader{
+				lc:   l,
+				mod:  mod,
+				root: filepath.Join(dirpath, "data"),
+			}
+
+			return ld, nil
+		}
+	}
+
+	return nil, fmt.Errorf("module %s is not in the system data directories",
+		modpath)
+}
+
+// Name implements the Locator interface.
+func (l systemLocator) Name() string {
+	return "fs:system"
+}
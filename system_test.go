@@ -0,0 +1,95 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// locateTest is a test case for a locator, loading the file a.txt.
+type locateTest struct {
+	modpath string
+	want    string // content of a.txt, or empty if the module is not located
+}
+
+// testLocate checks that the locator lc, named by name, locates the modules
+// in tests.
+func testLocate(t *testing.T, lc Locator, name string, tests []locateTest) {
+	t.Helper()
+
+	if got := lc.Name(); got != name {
+		t.Fatalf("got locator %q, want %q", got, name)
+	}
+	for _, test := range tests {
+		ld, err := lc.Locate(test.modpath)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%s: got no error", test.modpath)
+			} else if !strings.HasPrefix(err.Error(), "data: "+name+": ") {
+				t.Errorf("%s: got error %q, want locator %q", test.modpath,
+					err, name)
+			}
+
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.modpath, err)
+
+			continue
+		}
+		if got := ld.Module().Path; got != test.modpath {
+			t.Errorf("%s: got module %q", test.modpath, got)
+		}
+		got, err := readAll(ld, "a.txt")
+		if err != nil {
+			t.Errorf("%s: %v", test.modpath, err)
+		} else if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.modpath, got, test.want)
+		}
+	}
+}
+
+func TestSystemLocator(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "plan9", "windows":
+		t.Skipf("system data directories are fixed on %s", runtime.GOOS)
+	}
+	setInfo(t, testInfo)
+
+	// The main module and the dependency are in different directories, and
+	// relative directories are ignored.
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	writeFiles(t, dir1, map[string]string{
+		"go-data/example.com.dep@v1.0.0/data/a.txt": "dep",
+	})
+	writeFiles(t, dir2, map[string]string{
+		"app/data/a.txt": "main",
+		"go-data/example.com.dep@v1.0.0/data/a.txt": "shadowed",
+	})
+	dirs := strings.Join([]string{"relative", dir1, dir2}, string(os.PathListSeparator))
+	t.Setenv("XDG_DATA_DIRS", dirs)
+
+	testLocate(t, newSystemLocator(), "fs:system", []locateTest{
+		{"example.com/app", "main"},
+		{"example.com/dep", "dep"},
+		{"example.com/other", ""},
+	})
+
+	// The main module is not in the system data directories.
+	t.Setenv("XDG_DATA_DIRS", dir1)
+	if name := newSystemLocator().Name(); name != "null" {
+		t.Errorf("got locator %q, want %q", name, "null")
+	}
+
+	// The data directory is in the relative directory.
+	t.Setenv("XDG_DATA_DIRS", filepath.Base(dir2))
+	if name := newSystemLocator().Name(); name != "null" {
+		t.Errorf("relative directory: got locator %q, want %q", name, "null")
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// tar.go source file implements the "tar" locator.
+
+package data
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// NewTarLocator returns a new "tar" locator, for modules stored in the tar
+// archive at path, optionally compressed with gzip.  If path is relative, it
+// is relative to the directory containing the executable, with symbolic links
+// resolved.
+//
+// The archive uses the same layout as the user data directory: the main
+// module data is stored in <AppName>/data, and the data of active modules is
+// stored in go-data/<FlatPath>/data.
+//
+// The archive is indexed once.  If the archive is compressed, it is
+// decompressed to a temporary file, so that the files can be read by seeking.
+//
+// NewTarLocator returns the "null" locator if the archive can not be read or
+// the main module is not stored in the archive.
+func NewTarLocator(path string) Locator {
+	info := loadInfo()
+	if info == nil {
+		return &nullLocator{
+			err: errors.New("build info is not available"),
+		}
+	}
+	if !filepath.IsAbs(path) {
+		exedir, err := exedir()
+		if err != nil {
+			return &nullLocator{
+				err: err,
+			}
+		}
+		path = filepath.Join(exedir, path)
+	}
+
+	ar, err := newTarArchive(path)
+	if err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	return newArchiveLocator("tar", path, ar)
+}
+
+// newTarArchive returns a new archive for the tar archive named by file.
+func newTarArchive(file string) (*archive, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file is never closed, since it is used for the lifetime of the
+	// program.
+	r, err := openTarStream(f)
+	if err != nil {
+		f.Close()
+
+		return nil, err
+	}
+	fi, err := r.Stat()
+	if err != nil {
+		r.Close()
+
+		return nil, err
+	}
+
+	// Keep track of the offset of each file content, so that it can be read
+	// without scanning the archive again.
+	cr := &countingReader{
+		r: io.NewSectionReader(r, 0, fi.Size()),
+	}
+	tr := tar.NewReader(cr)
+	ar := newArchive()
+	entries := make(map[string]*tarEntry)
+	var links []*tar.Header
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			r.Close()
+
+			return nil, err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			ar.addDir(hdr.Name, hdr.FileInfo())
+		case tar.TypeReg, tar.TypeSymlink:
+			e := &tarEntry{
+				hdr: hdr,
+				r:   io.NewSectionReader(r, cr.n, hdr.Size),
+			}
+			ar.addFile(hdr.Name, e)
+			entries[path.Clean(hdr.Name)] = e
+		case tar.TypeLink:
+			links = append(links, hdr)
+		}
+	}
+
+	// Resolve hard links, that refer to a previous entry.
+	for _, hdr := range links {
+		target, ok := entries[path.Clean(hdr.Linkname)]
+		if !ok {
+			continue
+		}
+
+		link := *target.hdr
+		link.Name = hdr.Name
+		e := &tarEntry{
+			hdr: &link,
+			r:   target.r,
+		}
+		ar.addFile(hdr.Name, e)
+	}
+
+	return ar, nil
+}
+
+// openTarStream returns f, if it is not compressed, otherwise it returns a
+// temporary file with the decompressed content of f.
+func openTarStream(f *os.File) (*os.File, error) {
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		// Not a gzip file.  Let the tar reader report any error.
+		return f, nil
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp("", "go-data-*.tar")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary file as soon as possible.  This may fail on some
+	// systems, like Windows.
+	os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, zr); err != nil {
+		tmp.Close()
+
+		return nil, err
+	}
+	if err := zr.Close(); err != nil {
+		tmp.Close()
+
+		return nil, err
+	}
+
+	return tmp, nil
+}
+
+// tarEntry represents a regular file or symbolic link in a tar archive.
+type tarEntry struct {
+	hdr *tar.Header
+	r   *io.SectionReader // the file content
+}
+
+// FileInfo implements the archiveEntry interface.
+func (e *tarEntry) FileInfo() os.FileInfo {
+	return e.hdr.FileInfo()
+}
+
+// Open implements the archiveEntry interface.
+func (e *tarEntry) Open() (io.ReadCloser, error) {
+	// Create a new section reader, since a section reader has state.  The
+	// returned reader is a RandomReader.
+	r := io.NewSectionReader(e.r, 0, e.r.Size())
+
+	return nopCloser{r}, nil
+}
+
+// countingReader is an io.Reader that counts the number of bytes read.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// makeTar returns a tar archive with the specified files, optionally
+// compressed with gzip.  Each file is also stored as a hard link named by
+// adding the .link extension.
+func makeTar(t *testing.T, files map[string]string, compress bool) []byte {
+	t.Helper()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		content := files[name]
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range names {
+		hdr := &tar.Header{
+			Typeflag: tar.TypeLink,
+			Name:     name + ".link",
+			Linkname: name,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !compress {
+		return buf.Bytes()
+	}
+
+	var zbuf bytes.Buffer
+	zw := gzip.NewWriter(&zbuf)
+	if _, err := zw.Write(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return zbuf.Bytes()
+}
+
+func TestTarLocator(t *testing.T) {
+	setInfo(t, archiveInfo)
+
+	root := t.TempDir()
+	tests := []struct {
+		desc     string
+		name     string
+		compress bool
+	}{
+		{"plain", "data.tar", false},
+		{"gzip", "data.tar.gz", true},
+		{"gzip without extension", "data", true},
+	}
+	for _, test := range tests {
+		path := filepath.Join(root, test.name)
+		data := makeTar(t, archiveFiles, test.compress)
+		if err := os.WriteFile(path, data, 0666); err != nil {
+			t.Fatal(err)
+		}
+		lc := NewTarLocator(path)
+		testLocate(t, lc, "tar", archiveTests)
+
+		// Hard links have the content of their target.
+		if lc.Name() != "tar" {
+			continue
+		}
+		ld, err := lc.Locate("github.com/BurntSushi/toml")
+		if err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		if got, err := readAll(ld, "a.txt.link"); err != nil {
+			t.Errorf("%s: hard link: %v", test.desc, err)
+		} else if got != "toml" {
+			t.Errorf("%s: hard link: got %q, want %q", test.desc, got, "toml")
+		}
+	}
+
+	// Invalid archives.
+	writeFiles(t, root, map[string]string{
+		"invalid.tar":    "not a tar archive",
+		"invalid.tar.gz": "\x1f\x8bnot a gzip stream",
+	})
+	dep := makeTar(t, map[string]string{
+		"go-data/example.com.dep@v1.0.0/data/a.txt": "dep",
+	}, false)
+	if err := os.WriteFile(filepath.Join(root, "dep.tar"), dep, 0666); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"missing.tar", "invalid.tar", "invalid.tar.gz", "dep.tar",
+	} {
+		if lc := NewTarLocator(filepath.Join(root, name)); lc.Name() != "null" {
+			t.Errorf("%s: got locator %q, want %q", name, lc.Name(), "null")
+		}
+	}
+}
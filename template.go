@@ -0,0 +1,205 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// template.go source file implements the loading of text and HTML templates
+// from module data.
+
+package data
+
+import (
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"sync"
+	texttemplate "text/template"
+)
+
+// Templates loads text and HTML templates from the data of one or more
+// modules.
+//
+// The templates are associated with an unnamed root template, and they should
+// be executed with ExecuteTemplate.
+type Templates struct {
+	// Loaders are the loaders of the modules providing the templates.  If
+	// the same template name is defined using more than one loader, the
+	// definition from the last loader is used.
+	Loaders []Loader
+
+	// Patterns select the template files, using the syntax of Glob, like
+	// "tmpl/*.html".
+	Patterns []string
+
+	// Namespace, when true, causes the template names to be prefixed with
+	// the module path, like "example.com/mod/tmpl/index.html".  Otherwise
+	// the template names are the data file names, like "tmpl/index.html".
+	Namespace bool
+
+	// Funcs is the function map added to the templates before parsing.
+	Funcs map[string]interface{}
+
+	// Reload, when true and the main module is in development mode, causes
+	// the templates to be parsed again on each call to Text and HTML, so that
+	// changes to the template files are visible without restarting the
+	// program.
+	Reload bool
+
+	mu   sync.Mutex
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// ParseText parses the text templates in the data files loaded by ld and
+// matching patterns.
+func ParseText(ld Loader, patterns ...string) (*texttemplate.Template, error) {
+	ts := &Templates{
+		Loaders:  []Loader{ld},
+		Patterns: patterns,
+	}
+
+	return ts.Text()
+}
+
+// ParseHTML parses the HTML templates in the data files loaded by ld and
+// matching patterns.
+func ParseHTML(ld Loader, patterns ...string) (*htmltemplate.Template, error) {
+	ts := &Templates{
+		Loaders:  []Loader{ld},
+		Patterns: patterns,
+	}
+
+	return ts.HTML()
+}
+
+// Text returns the text templates.  The templates are parsed on the first
+// call, or on each call when reloading.
+func (ts *Templates) Text() (*texttemplate.Template, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.text != nil && !ts.reload() {
+		return ts.text, nil
+	}
+
+	files, err := ts.files()
+	if err != nil {
+		return nil, err
+	}
+	t := texttemplate.New("").Funcs(ts.Funcs)
+	for _, f := range files {
+		if _, err := t.New(f.name).Parse(f.text); err != nil {
+			return nil, mkerr(fileLocator(f.f), f.ld, f.f, "decode", err)
+		}
+	}
+	ts.text = t
+
+	return t, nil
+}
+
+// HTML returns the HTML templates.  The templates are parsed on the first
+// call, or on each call when reloading.
+func (ts *Templates) HTML() (*htmltemplate.Template, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.html != nil && !ts.reload() {
+		return ts.html, nil
+	}
+
+	files, err := ts.files()
+	if err != nil {
+		return nil, err
+	}
+	t := htmltemplate.New("").Funcs(ts.Funcs)
+	for _, f := range files {
+		if _, err := t.New(f.name).Parse(f.text); err != nil {
+			return nil, mkerr(fileLocator(f.f), f.ld, f.f, "decode", err)
+		}
+	}
+	ts.html = t
+
+	return t, nil
+}
+
+// reload returns true if the templates must be parsed again.
+func (ts *Templates) reload() bool {
+	info := loadInfo()
+
+	return ts.Reload && info != nil && info.Main.Version == "(devel)"
+}
+
+// templateFile is a template file read from module data.
+type templateFile struct {
+	name string // template name
+	text string // template content
+	ld   Loader
+	f    File
+}
+
+// files reads the template files, in the order they must be parsed.
+func (ts *Templates) files() ([]templateFile, error) {
+	var files []templateFile
+	for _, ld := range ts.Loaders {
+		prefix := ""
+		if ts.Namespace {
+			prefix = ld.Module().Path + "/"
+		}
+
+		for _, pattern := range ts.Patterns {
+			names, err := Glob(ld, pattern)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, name := range names {
+				f, err := ld.Load(name)
+				if err != nil {
+					return nil, err
+				}
+				text, err := readFile(f)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, templateFile{prefix + name, text, ld, f})
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("data: no template files match the patterns")
+	}
+
+	return files, nil
+}
+
+// readFile returns the content of the data file f.
+func readFile(f File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	buf, err := io.ReadAll(rc)
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
+
+// fileLocator returns the locator of the data file f, or nil if f is not
+// provided by a locator of this package.
+func fileLocator(f File) Locator {
+	switch uf := underlying(f).(type) {
+	case *fsFile:
+		return uf.lc
+	case *archiveFile:
+		return uf.lc
+	case *httpFile:
+		return uf.lc
+	case *decompressFile:
+		return uf.lc
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// testhook.go source file implements the hooks used by the datatest package.
+
+package data
+
+import "github.com/perillo/data/internal/testhook"
+
+func init() {
+	testhook.SetBuildInfo = setBuildInfo
+}
+
+// setBuildInfo implements testhook.SetBuildInfo.
+func setBuildInfo(path string, main testhook.Module, deps []testhook.Module) func() {
+	bi := &buildInfo{
+		Path: path,
+		Main: fromHook(&main),
+		Deps: make([]Module, len(deps)),
+	}
+	for i := range deps {
+		bi.Deps[i] = fromHook(&deps[i])
+	}
+
+	old := loadInfo()
+	storeInfo(bi)
+
+	return func() {
+		storeInfo(old)
+	}
+}
+
+// fromHook converts the module from testhook.Module to Module.
+func fromHook(m *testhook.Module) Module {
+	mod := Module{
+		Path:    m.Path,
+		Version: m.Version,
+		Sum:     m.Sum,
+	}
+	if m.Replace != nil {
+		// Replace is not recursive.
+		mod.Replace = &Module{
+			Path:    m.Replace.Path,
+			Version: m.Replace.Version,
+			Sum:     m.Replace.Sum,
+		}
+	}
+
+	return mod
+}
@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/perillo/data/internal/fsutil"
 )
 
 // userLocator implements the "fs:user" locator that locates a module in the
@@ -24,6 +26,8 @@ type userLocator struct {
 // newUserLocator returns the "null" locator if the user data directory is not
 // available or the application is not stored in the user data directory.
 func newUserLocator() Locator {
+	info := loadInfo()
+
 	godata, err := godata()
 	if err != nil {
 		return &nullLocator{
@@ -57,6 +61,8 @@ func (l *userLocator) Locate(modpath string) (Loader, error) {
 }
 
 func (l *userLocator) locate(modpath string) (Loader, error) {
+	info := loadInfo()
+
 	// Find module in build info.
 	mod, err := find(modpath)
 	if err != nil {
@@ -74,7 +80,7 @@ func (l *userLocator) locate(modpath string) (Loader, error) {
 		dirpath = filepath.Join(l.path, "go-data", mod.FlatPath())
 	}
 
-	if !isDir(dirpath) {
+	if !fsutil.IsDir(dirpath) {
 		return nil, fmt.Errorf("module %s is not in user data directory", modpath)
 	}
 
@@ -0,0 +1,206 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// vendor.go source file implements the "fs:vendor" locator.
+
+package data
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/perillo/data/internal/fsutil"
+	"github.com/perillo/data/internal/gocmd"
+	"github.com/perillo/data/internal/modfile"
+)
+
+// VendorDataDir is the name of the directory, in the main module root
+// directory, where the data of the vendored modules is stored by the
+// "go-data vendor" command.  The data of each module is stored in a
+// subdirectory named by the module path.
+//
+// The data is not stored in the vendor directory, since it is removed by go
+// mod vendor.
+const VendorDataDir = "vendor-data"
+
+// vendorLocator implements the "fs:vendor" locator that locates a module in
+// the main module vendor directory.
+type vendorLocator struct {
+	root string              // main module root directory
+	mods map[string]struct{} // vendored modules
+}
+
+// newVendorLocator returns a new "fs:vendor" locator, for modules listed in
+// vendor/modules.txt.
+//
+// newVendorLocator returns the "null" locator if the main module is not in
+// development mode, the main module root directory is not available, the go
+// command does not use the vendor directory or the main module has no vendor
+// directory.
+func newVendorLocator() Locator {
+	if err := develMode(loadInfo()); err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	root, err := modroot()
+	if err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+	if ok, err := vendorMode(root); err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	} else if !ok {
+		return &nullLocator{
+			err: errors.New("vendor mode is disabled"),
+		}
+	}
+
+	list, err := modfile.ParseVendor(filepath.Join(root, "vendor", "modules.txt"))
+	if err != nil {
+		return &nullLocator{
+			err: fmt.Errorf("vendor directory is not available: %v", err),
+		}
+	}
+
+	l := &vendorLocator{
+		root: root,
+		mods: make(map[string]struct{}, len(list)),
+	}
+	for _, mod := range list {
+		l.mods[mod.Path] = struct{}{}
+	}
+
+	return l
+}
+
+// Locate implements the Locator interface.
+func (l *vendorLocator) Locate(modpath string) (Loader, error) {
+	ld, err := l.locate(modpath)
+	if err != nil {
+		return nil, mkerr(l, err)
+	}
+
+	return ld, nil
+}
+
+func (l *vendorLocator) locate(modpath string) (Loader, error) {
+	info := loadInfo()
+
+	// Find module in build info.
+	mod, err := find(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The main module is special, and the data is stored in the module root
+	// directory.
+	root := filepath.Join(l.root, "data")
+	if modpath != info.Main.Path {
+		// Use modpath, since build info reports the replacement module.
+		if _, ok := l.mods[modpath]; !ok {
+			return nil, fmt.Errorf("module %s is not vendored", modpath)
+		}
+		root = filepath.Join(l.root, VendorDataDir, filepath.FromSlash(modpath))
+	}
+
+	// It is responsibility of Loader to report an error if the data directory
+	// does not exists.
+	ld := &fsLoader{
+		lc:   l,
+		mod:  mod,
+		root: root,
+	}
+
+	return ld, nil
+}
+
+// Name implements the Locator interface.
+func (l vendorLocator) Name() string {
+	return "fs:vendor"
+}
+
+// modroot returns the root directory of the main module, as reported by the
+// go command in the current directory.
+func modroot() (string, error) {
+	info := loadInfo()
+
+	root, err := gocmd.ModRoot()
+	if err != nil {
+		return "", fmt.Errorf("main module root directory is not available: %v", err)
+	}
+
+	// Check that the go.mod file is the one of the main module.
+	modpath, err := modfile.ModulePath(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	if modpath != info.Main.Path {
+		return "", fmt.Errorf("main module %s is not in the current directory",
+			&info.Main)
+	}
+
+	return root, nil
+}
+
+// vendorMode reports whether the go command builds the main module in root
+// with -mod=vendor.  As done by the go command, the -mod flag in GOFLAGS has
+// precedence; otherwise vendor mode is enabled when the main module has a
+// vendor directory and go.mod declares go 1.14 or later.
+func vendorMode(root string) (bool, error) {
+	goflags, err := gocmd.Getenv("GOFLAGS")
+	if err != nil {
+		return false, fmt.Errorf("GOFLAGS is not available: %v", err)
+	}
+	if mode := modFlag(goflags); mode != "" {
+		return mode == "vendor", nil
+	}
+
+	if !fsutil.IsDir(filepath.Join(root, "vendor")) {
+		return false, nil
+	}
+	version, err := modfile.GoVersion(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return false, err
+	}
+
+	return goVersionAtLeast(version, 14), nil
+}
+
+// modFlag returns the value of the -mod flag in goflags, or an empty string if
+// the flag is not set.
+func modFlag(goflags string) string {
+	mode := ""
+	for _, flag := range strings.Fields(goflags) {
+		// The flags can start with one or two dashes, and the last one wins.
+		flag = strings.TrimPrefix(strings.TrimPrefix(flag, "-"), "-")
+		if strings.HasPrefix(flag, "mod=") {
+			mode = flag[len("mod="):]
+		}
+	}
+
+	return mode
+}
+
+// goVersionAtLeast reports whether the Go version, like "1.16" or "1.21.0", is
+// at least 1.minor.
+func goVersionAtLeast(version string, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 || parts[0] != "1" {
+		return false
+	}
+	n, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+
+	return n >= minor
+}
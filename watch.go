@@ -0,0 +1,224 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// watch.go source file implements the watching of data files for changes.
+
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/perillo/data/internal/fsutil"
+)
+
+// pollInterval is the interval used by the polling watcher.
+const pollInterval = time.Second
+
+// Op describes a change to a data file.
+type Op int
+
+// These are the supported changes.
+const (
+	Create Op = iota + 1 // the file has been created
+	Write                // the file has been modified
+	Remove               // the file has been removed or renamed
+)
+
+// String implements the Stringer interface.
+func (op Op) String() string {
+	switch op {
+	case Create:
+		return "create"
+	case Write:
+		return "write"
+	case Remove:
+		return "remove"
+	}
+
+	return "unknown"
+}
+
+// Event represents a change to a data file.
+type Event struct {
+	Name string // the file name, relative to the data directory
+	Op   Op     // the change to the file
+}
+
+// Watcher reports the changes to the data files of a Loader.
+type Watcher struct {
+	// Events reports the changes to the data files.  It is closed when the
+	// watcher is closed.
+	Events <-chan Event
+
+	// Errors reports the errors occurred while watching.  It is closed when
+	// the watcher is closed.
+	Errors <-chan error
+
+	events chan Event
+	errors chan error
+	done   chan struct{}
+	once   sync.Once
+	close  func() error // close the underlying watcher, if any
+}
+
+// Watch returns a new Watcher that reports the changes to the data files of
+// the loader ld.  The changes are reported using inotify on Linux, and by
+// polling the data directory on the other systems or if inotify is not
+// available.
+//
+// Only loaders that store the data files on the local filesystem, like the
+// "fs:gopath" locator loaders, can be watched.
+func Watch(ld Loader) (*Watcher, error) {
+	f, err := ld.Load(".")
+	if err != nil {
+		return nil, err
+	}
+	root := f.Path()
+	if root == "" {
+		return nil, fmt.Errorf("data: %v: data is not stored on the local "+
+			"filesystem", ld.Module())
+	}
+	if !fsutil.IsDir(root) {
+		return nil, fmt.Errorf("data: %v: data directory does not exist",
+			ld.Module())
+	}
+
+	w := newWatcher()
+	if err := watchNative(w, root); err != nil {
+		// The directory tree is scanned before returning, so that the changes
+		// made after Watch returns are reported.
+		files, _ := scan(root)
+		go w.poll(root, files, pollInterval)
+	}
+
+	return w, nil
+}
+
+// newWatcher returns a new Watcher, without starting it.
+func newWatcher() *Watcher {
+	w := &Watcher{
+		events: make(chan Event),
+		errors: make(chan error),
+		done:   make(chan struct{}),
+	}
+	w.Events = w.events
+	w.Errors = w.errors
+
+	return w
+}
+
+// Close stops the watcher.
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		if w.close != nil {
+			err = w.close()
+		}
+	})
+
+	return err
+}
+
+// send sends the event ev, returning false if the watcher has been closed.
+func (w *Watcher) send(ev Event) bool {
+	select {
+	case w.events <- ev:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// sendError sends the error err, returning false if the watcher has been
+// closed.
+func (w *Watcher) sendError(err error) bool {
+	select {
+	case w.errors <- err:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// poll watches the directory tree rooted at root, by scanning it at every
+// interval and comparing the result with the previous scan, starting with
+// prev.  poll closes the channels on return.
+func (w *Watcher) poll(root string, prev map[string]os.FileInfo,
+	interval time.Duration) {
+	defer close(w.errors)
+	defer close(w.events)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.done:
+			return
+		}
+
+		cur, err := scan(root)
+		if err != nil && !w.sendError(err) {
+			return
+		}
+		for name, fi := range cur {
+			old, ok := prev[name]
+			switch {
+			case !ok:
+				if !w.send(Event{name, Create}) {
+					return
+				}
+			case !fi.ModTime().Equal(old.ModTime()) || fi.Size() != old.Size():
+				if fi.IsDir() {
+					continue
+				}
+				if !w.send(Event{name, Write}) {
+					return
+				}
+			}
+		}
+		for name := range prev {
+			if _, ok := cur[name]; !ok {
+				if !w.send(Event{name, Remove}) {
+					return
+				}
+			}
+		}
+		prev = cur
+	}
+}
+
+// scan returns information about all the files in the directory tree rooted
+// at root, indexed by their slash separated path relative to root.
+func scan(root string) (map[string]os.FileInfo, error) {
+	files := make(map[string]os.FileInfo)
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// The file may have been removed during the walk.
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = fi
+
+		return nil
+	})
+
+	return files, err
+}
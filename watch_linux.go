@@ -0,0 +1,185 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask is the mask of the events reported by inotify.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_DELETE_SELF
+
+// inotify implements a watcher using the Linux inotify API.
+type inotify struct {
+	w    *Watcher
+	fd   int              // the inotify instance
+	f    *os.File         // the inotify instance, for reading
+	root string           // absolute path to the data directory
+	dirs map[int32]string // watched directories, by watch descriptor
+	wds  map[string]int32 // watch descriptors, by directory
+}
+
+// watchNative starts watching the directory tree rooted at root, using the
+// inotify API.
+func watchNative(w *Watcher, root string) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return os.NewSyscallError("inotify_init1", err)
+	}
+
+	// Since the file descriptor is in non blocking mode, the file is
+	// pollable, and Close will unblock a pending Read.  Note that f.Fd must
+	// not be called, since it sets the file descriptor to blocking mode.
+	in := &inotify{
+		w:    w,
+		fd:   fd,
+		f:    os.NewFile(uintptr(fd), "inotify"),
+		root: root,
+		dirs: make(map[int32]string),
+		wds:  make(map[string]int32),
+	}
+	if err := in.addTree(root, false); err != nil {
+		in.f.Close()
+
+		return err
+	}
+	w.close = in.f.Close
+
+	go in.run()
+
+	return nil
+}
+
+// errClosed is returned by addTree when the watcher has been closed while
+// sending the Create events.
+var errClosed = errors.New("watcher closed")
+
+// addTree adds a watch for each directory in the tree rooted at dir.  If
+// created is true, a Create event is sent for each file in the tree, since
+// they may have been created before the watch was added.  In this case the
+// walk stops with errClosed as soon as the watcher is closed.
+func (in *inotify) addTree(dir string, created bool) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+		if created && path != dir && !in.send(path, Create) {
+			return errClosed
+		}
+		if !fi.IsDir() {
+			return nil
+		}
+
+		wd, err := syscall.InotifyAddWatch(in.fd, path, inotifyMask)
+		if err != nil {
+			return os.NewSyscallError("inotify_add_watch", err)
+		}
+		in.dirs[int32(wd)] = path
+		in.wds[path] = int32(wd)
+
+		return nil
+	})
+}
+
+// run reads the inotify events, until the watcher is closed.
+func (in *inotify) run() {
+	defer close(in.w.errors)
+	defer close(in.w.events)
+
+	var buf [4096 * syscall.SizeofInotifyEvent]byte
+	for {
+		n, err := in.f.Read(buf[:])
+		if err != nil {
+			select {
+			case <-in.w.done:
+			default:
+				in.w.sendError(err)
+			}
+
+			return
+		}
+
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			off += syscall.SizeofInotifyEvent
+			name := buf[off : off+int(ev.Len)]
+			off += int(ev.Len)
+
+			if !in.handle(ev, string(bytes.TrimRight(name, "\x00"))) {
+				return
+			}
+		}
+	}
+}
+
+// handle handles a single inotify event, returning false if the watcher has
+// been closed.
+func (in *inotify) handle(ev *syscall.InotifyEvent, name string) bool {
+	if ev.Mask&syscall.IN_Q_OVERFLOW != 0 {
+		return in.w.sendError(errors.New("inotify event queue overflow"))
+	}
+
+	dir, ok := in.dirs[ev.Wd]
+	if !ok {
+		return true
+	}
+	if ev.Mask&syscall.IN_DELETE_SELF != 0 {
+		delete(in.dirs, ev.Wd)
+		delete(in.wds, dir)
+
+		return true
+	}
+	path := filepath.Join(dir, name)
+
+	switch {
+	case ev.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		if !in.send(path, Create) {
+			return false
+		}
+		if ev.Mask&syscall.IN_ISDIR != 0 {
+			if err := in.addTree(path, true); err == errClosed {
+				return false
+			} else if err != nil {
+				return in.w.sendError(err)
+			}
+		}
+	case ev.Mask&syscall.IN_CLOSE_WRITE != 0:
+		return in.send(path, Write)
+	case ev.Mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+		if wd, ok := in.wds[path]; ok {
+			// The watch of a removed directory is automatically removed, but
+			// not the one of a moved directory.
+			syscall.InotifyRmWatch(in.fd, uint32(wd))
+			delete(in.dirs, wd)
+			delete(in.wds, path)
+		}
+
+		return in.send(path, Remove)
+	}
+
+	return true
+}
+
+// send sends an event for the file at path, returning false if the watcher
+// has been closed.
+func (in *inotify) send(path string, op Op) bool {
+	rel, err := filepath.Rel(in.root, path)
+	if err != nil {
+		return true
+	}
+
+	return in.w.send(Event{filepath.ToSlash(rel), op})
+}
@@ -0,0 +1,16 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package data
+
+import "errors"
+
+// watchNative starts watching the directory tree rooted at root, using the
+// native system support.  It is not supported on this system.
+func watchNative(w *Watcher, root string) error {
+	return errors.New("native watcher is not supported")
+}
@@ -0,0 +1,177 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// watchTimeout is the maximum time to wait for an event.
+const watchTimeout = 10 * time.Second
+
+// waitEvent waits until the watcher w reports the event want, ignoring the
+// other events.
+func waitEvent(t *testing.T, w *Watcher, want Event) {
+	t.Helper()
+
+	timeout := time.After(watchTimeout)
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				t.Fatalf("%v: watcher closed", want)
+			}
+			if ev == want {
+				return
+			}
+		case err := <-w.Errors:
+			t.Fatalf("%v: %v", want, err)
+		case <-timeout:
+			t.Fatalf("%v: timeout", want)
+		}
+	}
+}
+
+// watchTests are the changes to the data files, and the events reported by
+// the watcher.
+var watchTests = []struct {
+	desc   string
+	change func(root string) error
+	want   Event
+}{
+	{
+		"create",
+		func(root string) error {
+			return os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0666)
+		},
+		Event{"a.txt", Create},
+	},
+	{
+		"write",
+		func(root string) error {
+			return os.WriteFile(filepath.Join(root, "a.txt"), []byte("aa"), 0666)
+		},
+		Event{"a.txt", Write},
+	},
+	{
+		"create directory",
+		func(root string) error {
+			return os.Mkdir(filepath.Join(root, "dir"), 0777)
+		},
+		Event{"dir", Create},
+	},
+	{
+		"create in new directory",
+		func(root string) error {
+			return os.WriteFile(filepath.Join(root, "dir", "b.txt"), []byte("b"),
+				0666)
+		},
+		Event{"dir/b.txt", Create},
+	},
+	{
+		"write in new directory",
+		func(root string) error {
+			return os.WriteFile(filepath.Join(root, "dir", "b.txt"), []byte("bb"),
+				0666)
+		},
+		Event{"dir/b.txt", Write},
+	},
+	{
+		"remove",
+		func(root string) error {
+			return os.Remove(filepath.Join(root, "a.txt"))
+		},
+		Event{"a.txt", Remove},
+	},
+	{
+		"rename",
+		func(root string) error {
+			return os.Rename(filepath.Join(root, "dir", "b.txt"),
+				filepath.Join(root, "c.txt"))
+		},
+		Event{"dir/b.txt", Remove},
+	},
+}
+
+// testWatcher checks that the watcher w, watching the directory root, reports
+// the changes in watchTests.
+func testWatcher(t *testing.T, w *Watcher, root string) {
+	for _, test := range watchTests {
+		if err := test.change(root); err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		waitEvent(t, w, test.want)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for range w.Events {
+		// Drain the events sent before closing.
+	}
+}
+
+func TestWatch(t *testing.T) {
+	root := t.TempDir()
+	ld := &fsLoader{
+		lc:   namedLocator("fs:gopath"),
+		mod:  &Module{Path: "example.com/m", Version: "v1.0.0"},
+		root: root,
+	}
+	w, err := Watch(ld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testWatcher(t, w, root)
+}
+
+func TestWatchPoll(t *testing.T) {
+	// The polling watcher is used on the systems without native support, or
+	// if the native watcher can not be started.
+	root := t.TempDir()
+	w := newWatcher()
+	files, err := scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go w.poll(root, files, 10*time.Millisecond)
+	testWatcher(t, w, root)
+}
+
+func TestWatchUnsupported(t *testing.T) {
+	setInfo(t, testInfo)
+
+	zippath := filepath.Join(t.TempDir(), "data.zip")
+	content := makeZip(t, map[string]string{
+		"app/data/a.txt": "a",
+	})
+	if err := os.WriteFile(zippath, content, 0666); err != nil {
+		t.Fatal(err)
+	}
+	zipld, err := NewZipLocator(zippath).Locate(testInfo.Main.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		desc string
+		ld   Loader
+	}{
+		{"missing directory", &fsLoader{
+			lc:   namedLocator("fs:gopath"),
+			mod:  &testInfo.Main,
+			root: filepath.Join(t.TempDir(), "data"),
+		}},
+		{"not on the local filesystem", zipld},
+	}
+	for _, test := range tests {
+		if _, err := Watch(test.ld); err == nil {
+			t.Errorf("%s: got no error", test.desc)
+		}
+	}
+}
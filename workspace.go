@@ -0,0 +1,126 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// workspace.go source file implements the "fs:workspace" locator.
+
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/perillo/data/internal/gocmd"
+	"github.com/perillo/data/internal/modfile"
+)
+
+// workspaceLocator implements the "fs:workspace" locator that locates a module
+// in the go.work workspace.
+type workspaceLocator struct {
+	dirs map[string]string // module path to module root directory
+}
+
+// newWorkspaceLocator returns a new "fs:workspace" locator, for modules in the
+// go.work use list.
+//
+// newWorkspaceLocator returns the "null" locator if the main module is not in
+// development mode, there is no go.work file or the main module is not in the
+// workspace.
+func newWorkspaceLocator() Locator {
+	info := loadInfo()
+	if err := develMode(info); err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	gowork, err := gowork()
+	if err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	dirs, err := modfile.ParseWork(gowork)
+	if err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	l := &workspaceLocator{
+		dirs: dirs,
+	}
+
+	// Check if the main module is in the workspace.
+	if _, err := l.locate(info.Main.Path); err != nil {
+		return &nullLocator{
+			err: fmt.Errorf("main module %s is not in the workspace", &info.Main),
+		}
+	}
+
+	return l
+}
+
+// Locate implements the Locator interface.
+func (l *workspaceLocator) Locate(modpath string) (Loader, error) {
+	ld, err := l.locate(modpath)
+	if err != nil {
+		return nil, mkerr(l, err)
+	}
+
+	return ld, nil
+}
+
+func (l *workspaceLocator) locate(modpath string) (Loader, error) {
+	// Find module in build info.
+	mod, err := find(modpath)
+	if err != nil {
+		return nil, err
+	}
+
+	dirpath, ok := l.dirs[mod.Path]
+	if !ok {
+		return nil, fmt.Errorf("module %s is not in the workspace", modpath)
+	}
+
+	// It is responsibility of Loader to report an error if the data directory
+	// does not exists.
+	ld := &fsLoader{
+		lc:   l,
+		mod:  mod,
+		root: filepath.Join(dirpath, "data"),
+	}
+
+	return ld, nil
+}
+
+// Name implements the Locator interface.
+func (l workspaceLocator) Name() string {
+	return "fs:workspace"
+}
+
+// gowork returns the path to the go.work file.  The GOWORK environment
+// variable, when set, has precedence over the go command.
+func gowork() (string, error) {
+	value := os.Getenv("GOWORK")
+	if value == "" {
+		var err error
+
+		value, err = gocmd.Getenv("GOWORK")
+		if err != nil {
+			return "", fmt.Errorf("GOWORK is not available: %v", err)
+		}
+	}
+
+	switch value {
+	case "":
+		return "", errors.New("go.work file is not available")
+	case "off":
+		return "", errors.New("workspace mode is disabled")
+	}
+
+	return value, nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// zip.go source file implements the "zip" and "zip:exe" locators.
+
+package data
+
+import (
+	"archive/zip"
+	"errors"
+	"path/filepath"
+	"sync"
+)
+
+// NewZipLocator returns a new "zip" locator, for modules stored in the zip
+// archive at path.  If path is relative, it is relative to the directory
+// containing the executable, with symbolic links resolved.
+//
+// The archive uses the same layout as the user data directory: the main
+// module data is stored in <AppName>/data, and the data of active modules is
+// stored in go-data/<FlatPath>/data.
+//
+// NewZipLocator returns the "null" locator if the archive can not be opened or
+// the main module is not stored in the archive.
+func NewZipLocator(path string) Locator {
+	info := loadInfo()
+	if info == nil {
+		return &nullLocator{
+			err: errors.New("build info is not available"),
+		}
+	}
+	if !filepath.IsAbs(path) {
+		exedir, err := exedir()
+		if err != nil {
+			return &nullLocator{
+				err: err,
+			}
+		}
+		path = filepath.Join(exedir, path)
+	}
+
+	return newZipLocator("zip", path)
+}
+
+// newExezipLocator returns a new "zip:exe" locator, for modules stored in the
+// zip archive appended to the executable, as done by the "go-data bundle"
+// command.
+//
+// newExezipLocator returns the "null" locator if the executable path is not
+// available, the executable has no appended zip archive or the main module is
+// not stored in the archive.
+func newExezipLocator() Locator {
+	path, err := exepath()
+	if err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	return newZipLocator("zip:exe", path)
+}
+
+// newZipLocator returns a new zip locator with the specified name, for
+// modules stored in the zip archive at path.
+func newZipLocator(name, path string) Locator {
+	// The archive/zip package supports archives with prepended data, like
+	// an executable.
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return &nullLocator{
+			err: err,
+		}
+	}
+
+	// The archive is never closed, since it is used for the lifetime of the
+	// program.
+	return newArchiveLocator(name, path, newZipArchive(&r.Reader))
+}
+
+// newZipArchive returns a new archive for the zip archive read by r.
+func newZipArchive(r *zip.Reader) *archive {
+	ar := newArchive()
+	for _, zf := range r.File {
+		if zf.FileInfo().IsDir() {
+			ar.addDir(zf.Name, zf.FileInfo())
+		} else {
+			ar.addFile(zf.Name, zf)
+		}
+	}
+
+	return ar
+}
+
+// zipCache caches the open zip archives, indexed by path.
+type zipCache struct {
+	mu       sync.Mutex
+	archives map[string]*archive
+}
+
+// open returns the archive at path, opening it if necessary.
+func (c *zipCache) open(path string) (*archive, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ar, ok := c.archives[path]; ok {
+		return ar, nil
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	ar := newZipArchive(&r.Reader)
+	if c.archives == nil {
+		c.archives = make(map[string]*archive)
+	}
+	c.archives[path] = ar
+
+	return ar, nil
+}
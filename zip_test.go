@@ -0,0 +1,146 @@
+// Copyright 2020 Manlio Perillo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// archiveInfo is the build info used by the archive tests, with a dependency
+// whose module path and version need escaping in the module cache.
+var archiveInfo = buildInfo{
+	Path: "example.com/app/cmd/app",
+	Main: Module{Path: "example.com/app", Version: "v1.0.0"},
+	Deps: []Module{
+		{Path: "example.com/dep", Version: "v1.0.0"},
+		{Path: "github.com/BurntSushi/toml", Version: "v1.0.0-RC1"},
+	},
+}
+
+// archiveFiles are the files stored in the data archives, with the layout of
+// the user data directory.
+var archiveFiles = map[string]string{
+	"app/data/a.txt": "main",
+	"go-data/example.com.dep@v1.0.0/data/a.txt":                "dep",
+	"go-data/github.com.BurntSushi.toml@v1.0.0-RC1/data/a.txt": "toml",
+}
+
+// archiveTests are the locate tests for archiveFiles.
+var archiveTests = []locateTest{
+	{"example.com/app", "main"},
+	{"example.com/dep", "dep"},
+	{"github.com/BurntSushi/toml", "toml"},
+	{"github.com/burntsushi/toml", ""},
+	{"example.com/other", ""},
+}
+
+func TestZipLocator(t *testing.T) {
+	setInfo(t, archiveInfo)
+
+	root := t.TempDir()
+	zipdata := makeZip(t, archiveFiles)
+	zippath := filepath.Join(root, "data.zip")
+	if err := os.WriteFile(zippath, zipdata, 0666); err != nil {
+		t.Fatal(err)
+	}
+	testLocate(t, NewZipLocator(zippath), "zip", archiveTests)
+
+	// The archive is appended to an executable, as done by go-data bundle.
+	exepath := filepath.Join(root, "app.exe")
+	exedata := append([]byte("\x7fELF executable"), zipdata...)
+	if err := os.WriteFile(exepath, exedata, 0666); err != nil {
+		t.Fatal(err)
+	}
+	testLocate(t, newZipLocator("zip:exe", exepath), "zip:exe", archiveTests)
+
+	tests := []struct {
+		desc  string
+		path  string
+		files map[string]string // archive content, if not nil
+	}{
+		{"missing", "missing.zip", nil},
+		{"invalid", "invalid.zip", nil},
+		{"no main module", "dep.zip", map[string]string{
+			"go-data/example.com.dep@v1.0.0/data/a.txt": "dep",
+		}},
+	}
+	writeFiles(t, root, map[string]string{
+		"invalid.zip": "not a zip archive",
+	})
+	for _, test := range tests {
+		path := filepath.Join(root, test.path)
+		if test.files != nil {
+			if err := os.WriteFile(path, makeZip(t, test.files), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if name := NewZipLocator(path).Name(); name != "null" {
+			t.Errorf("%s: got locator %q, want %q", test.desc, name, "null")
+		}
+	}
+}
+
+func TestModzipLocator(t *testing.T) {
+	setInfo(t, archiveInfo)
+
+	// Module zip files are stored with the module path and version escaped,
+	// but the files they contain are not.
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"example.com/app/@v/v1.0.0.zip": string(makeZip(t, map[string]string{
+			"example.com/app@v1.0.0/data/a.txt": "main",
+		})),
+		"github.com/!burnt!sushi/toml/@v/v1.0.0-!r!c1.zip": string(makeZip(t,
+			map[string]string{
+				"github.com/BurntSushi/toml@v1.0.0-RC1/data/a.txt": "toml",
+			})),
+		"github.com/BurntSushi/toml/@v/v1.0.0-RC1.zip": "not escaped",
+	})
+	l := &modzipLocator{
+		path:  root,
+		cache: new(zipCache),
+	}
+
+	testLocate(t, l, "zip:modcache", []locateTest{
+		{"example.com/app", "main"},
+		{"github.com/BurntSushi/toml", "toml"},
+		{"example.com/dep", ""},
+		{"example.com/other", ""},
+	})
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string // escaped s, or empty for an error
+	}{
+		{"example.com/app", "example.com/app"},
+		{"github.com/BurntSushi/toml", "github.com/!burnt!sushi/toml"},
+		{"v1.0.0-RC1", "v1.0.0-!r!c1"},
+		{"", ""},
+		{"example.com/!app", ""},
+		{"example.com/àpp", ""},
+	}
+	for _, test := range tests {
+		got, err := escape(test.s)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("escape(%q): got %q, want an error", test.s, got)
+			}
+
+			continue
+		}
+		if err != nil {
+			t.Errorf("escape(%q): %v", test.s, err)
+
+			continue
+		}
+		if got != test.want {
+			t.Errorf("escape(%q): got %q, want %q", test.s, got, test.want)
+		}
+	}
+}